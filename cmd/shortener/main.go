@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"github.com/bluele/gcache"
 	log "github.com/sirupsen/logrus"
 	"net/http"
 	"os"
@@ -10,7 +9,6 @@ import (
 
 	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
 	"github.com/sergiusd/go-scanty-url-shortener/internal/handler"
-	"github.com/sergiusd/go-scanty-url-shortener/internal/storage"
 )
 
 func main() {
@@ -28,19 +26,15 @@ func main() {
 	log.SetLevel(logLevel)
 	log.Infof("Log level: %s", conf.LogLevel)
 
-	// connect to storage service
-	storageSrv, err := storage.New(conf.Storage)
+	app, err := handler.NewApp(conf)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	cache := gcache.New(conf.Cache.Size).ARC().Build()
-	log.Infof("Cache size: %v", conf.Cache.Size)
-
 	// configure http server
 	server := &http.Server{
 		Addr:    ":" + conf.Server.Port,
-		Handler: handler.New(conf.Server, storageSrv, cache),
+		Handler: app.Handler,
 	}
 
 	stop := make(chan os.Signal, 1)
@@ -61,14 +55,16 @@ func main() {
 	// waiting http server error or Ctrl+C
 	select {
 	case <-serverError:
-		_ = storageSrv.Close()
+		// handlers of accepted requests may still record clicks
+		_ = server.Shutdown(context.Background())
+		app.Close()
 		// server already failed with error
 		log.Infoln("Server stopped")
 		return
 	case <-stop:
 		log.Infoln("Ctrl+C pressed")
-		_ = storageSrv.Close()
 		_ = server.Shutdown(context.Background())
+		app.Close()
 		<-serverError // waiting server shutdown
 		log.Infoln("Server stopped")
 		return
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
 	"sync"
@@ -17,6 +18,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/handler"
 )
 
 var expires = time.Now().Add(time.Hour).Format(time.RFC3339)
@@ -37,11 +39,7 @@ func Test_App(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	host := "http://localhost"
-	if envHost := os.Getenv("SHORTENER_SERVER_HOST"); envHost != "" {
-		host = envHost
-	}
-	endpoint := host + ":" + conf.Server.Port
+	endpoint := startServer(t, conf)
 	token := conf.Server.Token
 
 	prefix := time.Now().Unix()
@@ -105,6 +103,49 @@ func Test_App(t *testing.T) {
 		assert.Equal(t, int32(0), errorCount1.Load(), "First requests errors")
 		assert.Equal(t, int32(0), errorCount2.Load(), "Repeated requests errors")
 	})
+
+	t.Run("Create batch "+strconv.Itoa(itemCount), func(t *testing.T) {
+		urls := make([]string, itemCount)
+		for i := range urls {
+			urls[i] = getUrl(goroutineCount, i)
+		}
+		shortUrls, err := createBatchRequest(endpoint, token, urls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, shortUrls, itemCount)
+		for i, shortUrl := range shortUrls {
+			longUrl, err := redirectRequest(shortUrl)
+			assert.NoError(t, err, "short = %v", shortUrl)
+			assert.Equal(t, urls[i], longUrl, "short = %v", shortUrl)
+		}
+	})
+}
+
+// startServer returns endpoint of running server from SHORTENER_SERVER_HOST,
+// otherwise starts in-process server with memory storage
+func startServer(t *testing.T, conf *config.Config) string {
+	if envHost := os.Getenv("SHORTENER_SERVER_HOST"); envHost != "" {
+		return envHost + ":" + conf.Server.Port
+	}
+
+	conf.Storage.Kind = "memory"
+	conf.Storage.Memory.Path = ""
+	server := httptest.NewUnstartedServer(nil)
+	conf.Server.Schema = "http"
+	conf.Server.Prefix = server.Listener.Addr().String()
+	app, err := handler.NewApp(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Config.Handler = app.Handler
+	server.Start()
+	t.Cleanup(func() {
+		server.Close()
+		app.Close()
+	})
+
+	return server.URL
 }
 
 func createRequest(endpoint string, token string, url string) (string, error) {
@@ -135,6 +176,43 @@ func createRequest(endpoint string, token string, url string) (string, error) {
 	return msg.Data.(string), nil
 }
 
+func createBatchRequest(endpoint string, token string, urls []string) ([]string, error) {
+	items := make([]map[string]string, len(urls))
+	for i, url := range urls {
+		items[i] = map[string]string{"url": url, "expires": expires}
+	}
+	body, _ := json.Marshal(items)
+	req, _ := http.NewRequest("POST", endpoint+"/api/v1/links:batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Token", token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var msg struct {
+		Success bool `json:"success"`
+		Data    []struct {
+			ShortURL string `json:"shortUrl"`
+			Error    string `json:"error"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("Can't parse response: %w", err)
+	}
+	if !msg.Success {
+		return nil, errors.New(fmt.Sprintf("Not success status code %v", resp.StatusCode))
+	}
+	ret := make([]string, len(msg.Data))
+	for i, item := range msg.Data {
+		if item.Error != "" {
+			return nil, errors.New(item.Error)
+		}
+		ret[i] = item.ShortURL
+	}
+	return ret, nil
+}
+
 func redirectRequest(url string) (string, error) {
 	client := &http.Client{
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -146,6 +224,8 @@ func redirectRequest(url string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
 	if resp.StatusCode != 301 {
 		return "", errors.New(fmt.Sprintf("Not redirect status code %v", resp.StatusCode))
 	}
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+const (
+	ScopeCreate = "create"
+	ScopeRead   = "read"
+	ScopeManage = "manage"
+	// ScopeAdmin grants all scopes, access to links of all owners and token management
+	ScopeAdmin = "admin"
+)
+
+var Scopes = []string{ScopeCreate, ScopeRead, ScopeManage, ScopeAdmin}
+
+// ConfigTokenName is name of admin token from server configuration
+const ConfigTokenName = "config"
+
+const defaultRefreshInterval = 10 * time.Second
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+type tokenStorage interface {
+	SaveToken(token model.Token) error
+	DeleteToken(name string) error
+	ListTokens() ([]model.Token, error)
+}
+
+// Registry keeps tokens in memory and reloads them from storage periodically,
+// so tokens issued or revoked by other instances are applied without restart
+type Registry struct {
+	storage    tokenStorage
+	configHash string
+	mu         sync.RWMutex
+	tokens     []model.Token
+	// generation is bumped on every mutation, so reload doesn't replace tokens changed during storage read
+	generation uint64
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// New loads tokens from storage, configToken is admin token, it is disabled if empty
+func New(configToken string, storage tokenStorage, refreshInterval time.Duration) (*Registry, error) {
+	r := &Registry{
+		storage: storage,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if configToken != "" {
+		r.configHash = Hash(configToken)
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	if refreshInterval <= 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+	go r.run(refreshInterval)
+	return r, nil
+}
+
+// Hash returns hex of sha256 of token secret
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasScope reports whether token grants scope
+func HasScope(token model.Token, scope string) bool {
+	return slices.Contains(token.Scopes, scope) || slices.Contains(token.Scopes, ScopeAdmin)
+}
+
+// Validate checks name and scopes of new token
+func Validate(name string, scopes []string) error {
+	if !namePattern.MatchString(name) {
+		return errors.New("Token name must be 1-64 characters of a-z, A-Z, 0-9, - and _")
+	}
+	if name == ConfigTokenName {
+		return errors.Errorf("Token name %v is reserved", name)
+	}
+	if len(scopes) == 0 {
+		return errors.New("Token must have scopes")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return errors.Errorf("Unknown scope %v", scope)
+		}
+	}
+	return nil
+}
+
+func (r *Registry) run(refreshInterval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.reload(); err != nil {
+				log.Errorf("Can't reload tokens: %+v", err)
+			}
+		}
+	}
+}
+
+func (r *Registry) reload() error {
+	r.mu.RLock()
+	generation := r.generation
+	r.mu.RUnlock()
+
+	tokens, err := r.storage.ListTokens()
+	if err != nil {
+		return errors.Wrap(err, "Can't load tokens")
+	}
+	r.mu.Lock()
+	if r.generation == generation {
+		r.tokens = tokens
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// Authenticate returns token by secret, hashes are compared in constant time
+func (r *Registry) Authenticate(secret string) (model.Token, bool) {
+	if secret == "" {
+		return model.Token{}, false
+	}
+	hash := []byte(Hash(secret))
+	if r.configHash != "" && subtle.ConstantTimeCompare(hash, []byte(r.configHash)) == 1 {
+		return model.Token{Name: ConfigTokenName, Scopes: []string{ScopeAdmin}}, true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ret model.Token
+	found := false
+	for _, token := range r.tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(token.Hash)) == 1 {
+			ret, found = token, true
+		}
+	}
+	return ret, found
+}
+
+// Issue creates token and returns its secret, which is not stored anywhere
+func (r *Registry) Issue(name string, scopes []string) (model.Token, string, error) {
+	var buf [32]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return model.Token{}, "", errors.Wrap(err, "Can't generate token")
+	}
+	secret := base64.RawURLEncoding.EncodeToString(buf[:])
+	token := model.Token{
+		Name:    name,
+		Hash:    Hash(secret),
+		Scopes:  scopes,
+		Created: time.Now(),
+	}
+	if err := r.storage.SaveToken(token); err != nil {
+		return model.Token{}, "", err
+	}
+
+	r.mu.Lock()
+	r.tokens = append(r.tokens, token)
+	r.generation++
+	r.mu.Unlock()
+	return token, secret, nil
+}
+
+// Revoke deletes token, other instances stop accepting it after refresh interval
+func (r *Registry) Revoke(name string) error {
+	if err := r.storage.DeleteToken(name); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.tokens = slices.DeleteFunc(slices.Clone(r.tokens), func(token model.Token) bool {
+		return token.Name == name
+	})
+	r.generation++
+	r.mu.Unlock()
+	return nil
+}
+
+// List returns tokens from storage
+func (r *Registry) List() ([]model.Token, error) {
+	return r.storage.ListTokens()
+}
+
+func (r *Registry) Close() {
+	close(r.stop)
+	<-r.done
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+type testStorage struct {
+	mu     sync.Mutex
+	tokens []model.Token
+}
+
+func (s *testStorage) SaveToken(token model.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tokens {
+		if t.Name == token.Name {
+			return model.ErrTokenDuplicated
+		}
+	}
+	s.tokens = append(s.tokens, token)
+	return nil
+}
+
+func (s *testStorage) DeleteToken(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.tokens {
+		if t.Name == name {
+			s.tokens = append(s.tokens[:i:i], s.tokens[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("no token")
+}
+
+func (s *testStorage) ListTokens() ([]model.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]model.Token(nil), s.tokens...), nil
+}
+
+func newTestRegistry(t *testing.T, configToken string, storage tokenStorage) *Registry {
+	r, err := New(configToken, storage, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{name: "granted", scopes: []string{ScopeCreate, ScopeRead}, scope: ScopeRead, want: true},
+		{name: "not granted", scopes: []string{ScopeCreate}, scope: ScopeManage},
+		{name: "admin grants all", scopes: []string{ScopeAdmin}, scope: ScopeManage, want: true},
+		{name: "no scopes", scope: ScopeRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HasScope(model.Token{Scopes: tt.scopes}, tt.scope))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenName string
+		scopes    []string
+		valid     bool
+	}{
+		{name: "valid", tokenName: "ci-bot_1", scopes: []string{ScopeCreate}, valid: true},
+		{name: "empty name", scopes: []string{ScopeCreate}},
+		{name: "invalid name", tokenName: "ci bot", scopes: []string{ScopeCreate}},
+		{name: "reserved name", tokenName: ConfigTokenName, scopes: []string{ScopeCreate}},
+		{name: "no scopes", tokenName: "bot"},
+		{name: "unknown scope", tokenName: "bot", scopes: []string{"delete"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.tokenName, tt.scopes)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestRegistry_Authenticate(t *testing.T) {
+	r := newTestRegistry(t, "changeme", &testStorage{})
+	token, secret, err := r.Issue("bot", []string{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, token.Hash, secret, "secret isn't stored")
+
+	tests := []struct {
+		name   string
+		secret string
+		want   string
+		ok     bool
+	}{
+		{name: "config token", secret: "changeme", want: ConfigTokenName, ok: true},
+		{name: "issued token", secret: secret, want: "bot", ok: true},
+		{name: "unknown token", secret: "unknown"},
+		{name: "empty token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, ok := r.Authenticate(tt.secret)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, token.Name)
+		})
+	}
+}
+
+func TestRegistry_WithoutConfigToken(t *testing.T) {
+	r := newTestRegistry(t, "", &testStorage{})
+	_, ok := r.Authenticate("")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Revoke(t *testing.T) {
+	storage := &testStorage{}
+	r := newTestRegistry(t, "", storage)
+	_, secret, err := r.Issue("bot", []string{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = r.Issue("bot", []string{ScopeRead})
+	assert.ErrorIs(t, err, model.ErrTokenDuplicated)
+
+	assert.NoError(t, r.Revoke("bot"))
+	_, ok := r.Authenticate(secret)
+	assert.False(t, ok)
+	tokens, err := r.List()
+	assert.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestRegistry_ReloadAppliesOtherInstances(t *testing.T) {
+	storage := &testStorage{}
+	r := newTestRegistry(t, "", storage)
+	other := newTestRegistry(t, "", storage)
+	_, secret, err := other.Issue("bot", []string{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ok := r.Authenticate(secret)
+	assert.False(t, ok, "token isn't known before reload")
+	assert.NoError(t, r.reload())
+	_, ok = r.Authenticate(secret)
+	assert.True(t, ok, "token is known after reload")
+}
+
+// blockingStorage holds listing of tokens until release is closed
+type blockingStorage struct {
+	testStorage
+	listing chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStorage) ListTokens() ([]model.Token, error) {
+	tokens, err := s.testStorage.ListTokens()
+	s.listing <- struct{}{}
+	<-s.release
+	return tokens, err
+}
+
+func TestRegistry_ReloadKeepsConcurrentIssue(t *testing.T) {
+	storage := &blockingStorage{listing: make(chan struct{}, 1), release: make(chan struct{})}
+	close(storage.release)
+	r := newTestRegistry(t, "", storage)
+	<-storage.listing
+	storage.release = make(chan struct{})
+
+	reloaded := make(chan error)
+	go func() { reloaded <- r.reload() }()
+	// token is issued after storage is read by reload
+	<-storage.listing
+	_, secret, err := r.Issue("bot", []string{ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(storage.release)
+	assert.NoError(t, <-reloaded)
+
+	_, ok := r.Authenticate(secret)
+	assert.True(t, ok)
+}
@@ -2,37 +2,152 @@ package base62
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"strings"
+	"unicode"
 )
 
-const (
-	alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	length   = uint64(len(alphabet))
-)
+const DefaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
-func Encode(number uint64) string {
+var defaultCodec, _ = NewCodec(DefaultAlphabet, 0, false)
+
+// Codec encodes ids to short codes with custom alphabet, the least significant digit goes first
+type Codec struct {
+	alphabet        string
+	base            uint64
+	index           [256]int16
+	length          int
+	caseInsensitive bool
+}
+
+// NewCodec returns codec for alphabet of unique ascii symbols, length is the target length of codes
+// or zero to use whole uint64 range, in case insensitive mode alphabet is lowercased
+func NewCodec(alphabet string, length int, caseInsensitive bool) (*Codec, error) {
+	if alphabet == "" {
+		alphabet = DefaultAlphabet
+	}
+	c := &Codec{length: length, caseInsensitive: caseInsensitive}
+	for i := range c.index {
+		c.index[i] = -1
+	}
+	var builder strings.Builder
+	for _, symbol := range alphabet {
+		if symbol > unicode.MaxASCII {
+			return nil, fmt.Errorf("alphabet has non ascii character %q", symbol)
+		}
+		if caseInsensitive {
+			symbol = toLower(symbol)
+		}
+		if c.index[symbol] != -1 {
+			if caseInsensitive {
+				continue
+			}
+			return nil, fmt.Errorf("alphabet has duplicated character %q", symbol)
+		}
+		c.index[symbol] = int16(builder.Len())
+		builder.WriteRune(symbol)
+	}
+	c.alphabet = builder.String()
+	c.base = uint64(len(c.alphabet))
+	if c.base < 2 {
+		return nil, errors.New("alphabet must have at least 2 characters")
+	}
+	if caseInsensitive {
+		for symbol := 'A'; symbol <= 'Z'; symbol++ {
+			c.index[symbol] = c.index[toLower(symbol)]
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("code length must not be negative")
+	}
+	if length > 0 && c.pow(length-1) == 0 {
+		return nil, fmt.Errorf("code length %v overflows uint64 for alphabet of %v characters", length, c.base)
+	}
+	return c, nil
+}
+
+func toLower(symbol rune) rune {
+	if symbol >= 'A' && symbol <= 'Z' {
+		return symbol + 'a' - 'A'
+	}
+	return symbol
+}
+
+// pow returns base^exp, zero if it overflows uint64
+func (c *Codec) pow(exp int) uint64 {
+	ret := uint64(1)
+	for i := 0; i < exp; i++ {
+		if ret > math.MaxUint64/c.base {
+			return 0
+		}
+		ret *= c.base
+	}
+	return ret
+}
+
+// Length returns target length of codes, zero if it is not set
+func (c *Codec) Length() int {
+	return c.length
+}
+
+// IDRange returns inclusive range of ids, which are encoded to codes of target length
+func (c *Codec) IDRange() (uint64, uint64) {
+	if c.length == 0 {
+		return 1, math.MaxUint64
+	}
+	return c.LengthRange(c.length)
+}
+
+// LengthRange returns inclusive range of ids, which are encoded to codes of length,
+// min is zero if there are no such ids in uint64
+func (c *Codec) LengthRange(length int) (uint64, uint64) {
+	min := c.pow(length - 1)
+	if min == 0 {
+		return 0, 0
+	}
+	max := c.pow(length)
+	if max == 0 {
+		return min, math.MaxUint64
+	}
+	return min, max - 1
+}
+
+func (c *Codec) Encode(number uint64) string {
 	var encodedBuilder strings.Builder
 	encodedBuilder.Grow(11)
 
-	for ; number > 0; number = number / length {
-		encodedBuilder.WriteByte(alphabet[(number % length)])
+	for ; number > 0; number = number / c.base {
+		encodedBuilder.WriteByte(c.alphabet[(number % c.base)])
 	}
 
 	return encodedBuilder.String()
 }
 
-func Decode(encoded string) (uint64, error) {
+// Decode returns id of code, it fails on unknown characters and on uint64 overflow
+func (c *Codec) Decode(encoded string) (uint64, error) {
 	var number uint64
 
-	for i, symbol := range encoded {
-		alphabeticPosition := strings.IndexRune(alphabet, symbol)
+	for i := len(encoded) - 1; i >= 0; i-- {
+		alphabeticPosition := c.index[encoded[i]]
 
 		if alphabeticPosition == -1 {
-			return uint64(alphabeticPosition), errors.New("invalid character: " + string(symbol))
+			return 0, errors.New("invalid character: " + string(encoded[i]))
 		}
-		number += uint64(alphabeticPosition) * uint64(math.Pow(float64(length), float64(i)))
+		digit := uint64(alphabeticPosition)
+		if number > (math.MaxUint64-digit)/c.base {
+			return 0, errors.New("code overflows uint64: " + encoded)
+		}
+		number = number*c.base + digit
 	}
 
 	return number, nil
 }
+
+func Encode(number uint64) string {
+	return defaultCodec.Encode(number)
+}
+
+func Decode(encoded string) (uint64, error) {
+	return defaultCodec.Decode(encoded)
+}
@@ -0,0 +1,107 @@
+package base62
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodec_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name            string
+		alphabet        string
+		caseInsensitive bool
+	}{
+		{name: "default", alphabet: DefaultAlphabet},
+		{name: "binary", alphabet: "01"},
+		{name: "custom", alphabet: "23456789bcdfghjkmnpqrstvwxz"},
+		{name: "case insensitive", alphabet: DefaultAlphabet, caseInsensitive: true},
+	}
+	numbers := []uint64{0, 1, 61, 62, 63, 3843, 3844, 1 << 32, math.MaxUint64 - 1, math.MaxUint64}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, err := NewCodec(tt.alphabet, 0, tt.caseInsensitive)
+			if !assert.NoError(t, err) {
+				return
+			}
+			for _, number := range numbers {
+				decoded, err := codec.Decode(codec.Encode(number))
+				assert.NoError(t, err, number)
+				assert.Equal(t, number, decoded)
+			}
+		})
+	}
+}
+
+func TestCodec_Decode(t *testing.T) {
+	insensitive, err := NewCodec(DefaultAlphabet, 0, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// the most significant digit of max uint64 is the last one
+	maxCode := Encode(math.MaxUint64)
+	assert.Equal(t, "v", maxCode[len(maxCode)-1:])
+	tests := []struct {
+		name    string
+		codec   *Codec
+		code    string
+		want    uint64
+		wantErr bool
+	}{
+		{name: "max uint64", codec: defaultCodec, code: maxCode, want: math.MaxUint64},
+		{name: "overflow by digit", codec: defaultCodec, code: maxCode[:len(maxCode)-1] + "w", wantErr: true},
+		{name: "overflow by length", codec: defaultCodec, code: "999999999999", wantErr: true},
+		{name: "trailing zero digits", codec: defaultCodec, code: "baa", want: 1},
+		{name: "unknown character", codec: defaultCodec, code: "ab-c", wantErr: true},
+		{name: "case sensitive", codec: defaultCodec, code: "B", want: 27},
+		{name: "case folding upper", codec: insensitive, code: "ABC", want: 0 + 1*36 + 2*36*36},
+		{name: "case folding mixed", codec: insensitive, code: "aBc", want: 0 + 1*36 + 2*36*36},
+		{name: "case folding digits", codec: insensitive, code: "0", want: 26},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.codec.Decode(tt.code)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewCodec(t *testing.T) {
+	tests := []struct {
+		name            string
+		alphabet        string
+		length          int
+		caseInsensitive bool
+		wantAlphabet    string
+		wantErr         bool
+	}{
+		{name: "default alphabet", alphabet: "", wantAlphabet: DefaultAlphabet},
+		{name: "duplicated character", alphabet: "abca", wantErr: true},
+		{name: "non ascii character", alphabet: "abcж", wantErr: true},
+		{name: "single character", alphabet: "a", wantErr: true},
+		{name: "case folding", alphabet: "abcABC", caseInsensitive: true, wantAlphabet: "abc"},
+		{name: "case folding to single character", alphabet: "aA", caseInsensitive: true, wantErr: true},
+		{name: "negative length", alphabet: DefaultAlphabet, length: -1, wantErr: true},
+		{name: "max length", alphabet: DefaultAlphabet, length: 11, wantAlphabet: DefaultAlphabet},
+		{name: "length overflow", alphabet: DefaultAlphabet, length: 12, wantErr: true},
+		{name: "binary length overflow", alphabet: "01", length: 65, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec, err := NewCodec(tt.alphabet, tt.length, tt.caseInsensitive)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.wantAlphabet, codec.alphabet)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package campaign
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+const defaultRefreshInterval = 10 * time.Second
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+type campaignStorage interface {
+	SaveCampaign(campaign model.Campaign) error
+	DeleteCampaign(name string) error
+	ListCampaigns() ([]model.Campaign, error)
+}
+
+// Registry keeps campaigns in memory and reloads them from storage periodically,
+// so campaigns changed by other instances are applied to redirects without restart
+type Registry struct {
+	storage   campaignStorage
+	mu        sync.RWMutex
+	campaigns map[string]model.Campaign
+	// generation is bumped on every mutation, so reload doesn't replace campaigns changed during storage read
+	generation uint64
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// New loads campaigns from storage
+func New(storage campaignStorage, refreshInterval time.Duration) (*Registry, error) {
+	r := &Registry{
+		storage: storage,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	if refreshInterval <= 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+	go r.run(refreshInterval)
+	return r, nil
+}
+
+// Validate checks name and parameters of campaign
+func Validate(name string, params map[string]string) error {
+	if !namePattern.MatchString(name) {
+		return errors.New("Campaign name must be 1-64 characters of a-z, A-Z, 0-9, - and _")
+	}
+	if len(params) == 0 {
+		return errors.New("Campaign must have parameters")
+	}
+	for param := range params {
+		if param == "" {
+			return errors.New("Campaign parameter name must not be empty")
+		}
+	}
+	return nil
+}
+
+func (r *Registry) run(refreshInterval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.reload(); err != nil {
+				log.Errorf("Can't reload campaigns: %+v", err)
+			}
+		}
+	}
+}
+
+func (r *Registry) reload() error {
+	r.mu.RLock()
+	generation := r.generation
+	r.mu.RUnlock()
+
+	list, err := r.storage.ListCampaigns()
+	if err != nil {
+		return errors.Wrap(err, "Can't load campaigns")
+	}
+	campaigns := make(map[string]model.Campaign, len(list))
+	for _, campaign := range list {
+		campaigns[campaign.Name] = campaign
+	}
+	r.mu.Lock()
+	if r.generation == generation {
+		r.campaigns = campaigns
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// Get returns campaign by name
+func (r *Registry) Get(name string) (model.Campaign, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	campaign, ok := r.campaigns[name]
+	return campaign, ok
+}
+
+// Save creates or replaces campaign, replaced parameters are applied to redirects of all its links
+func (r *Registry) Save(name string, params map[string]string) (model.Campaign, error) {
+	campaign := model.Campaign{
+		Name:    name,
+		Params:  params,
+		Created: time.Now(),
+	}
+	if err := r.storage.SaveCampaign(campaign); err != nil {
+		return model.Campaign{}, err
+	}
+
+	r.mu.Lock()
+	if old, ok := r.campaigns[name]; ok {
+		campaign.Created = old.Created
+	}
+	r.campaigns[name] = campaign
+	r.generation++
+	r.mu.Unlock()
+	return campaign, nil
+}
+
+// Delete deletes campaign, links of campaign are redirected without its parameters
+func (r *Registry) Delete(name string) error {
+	if err := r.storage.DeleteCampaign(name); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.campaigns, name)
+	r.generation++
+	r.mu.Unlock()
+	return nil
+}
+
+// List returns campaigns from storage
+func (r *Registry) List() ([]model.Campaign, error) {
+	return r.storage.ListCampaigns()
+}
+
+func (r *Registry) Close() {
+	close(r.stop)
+	<-r.done
+}
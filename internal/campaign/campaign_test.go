@@ -0,0 +1,92 @@
+package campaign
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/storage/memory"
+)
+
+func newTestRegistry(t *testing.T, storage campaignStorage) *Registry {
+	r, err := New(storage, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Close)
+	return r
+}
+
+func newTestStorage(t *testing.T) campaignStorage {
+	m, err := memory.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		campaignName string
+		params       map[string]string
+		valid        bool
+	}{
+		{name: "valid", campaignName: "spring-sale_1", params: map[string]string{"utm_source": "mail"}, valid: true},
+		{name: "empty name", params: map[string]string{"utm_source": "mail"}},
+		{name: "invalid name", campaignName: "spring sale", params: map[string]string{"utm_source": "mail"}},
+		{name: "no params", campaignName: "sale"},
+		{name: "empty param name", campaignName: "sale", params: map[string]string{"": "mail"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.campaignName, tt.params)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestRegistry_SaveAndDelete(t *testing.T) {
+	r := newTestRegistry(t, newTestStorage(t))
+	created, err := r.Save("sale", map[string]string{"utm_source": "mail"})
+	assert.NoError(t, err)
+
+	// replaced campaign keeps creation time
+	replaced, err := r.Save("sale", map[string]string{"utm_source": "push"})
+	assert.NoError(t, err)
+	assert.Equal(t, created.Created, replaced.Created)
+	campaign, ok := r.Get("sale")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"utm_source": "push"}, campaign.Params)
+
+	assert.NoError(t, r.Delete("sale"))
+	_, ok = r.Get("sale")
+	assert.False(t, ok)
+	assert.ErrorIs(t, r.Delete("sale"), model.ErrNoCampaign)
+}
+
+func TestRegistry_ReloadAppliesOtherInstances(t *testing.T) {
+	storage := newTestStorage(t)
+	r := newTestRegistry(t, storage)
+	other := newTestRegistry(t, storage)
+	_, err := other.Save("sale", map[string]string{"utm_source": "mail"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ok := r.Get("sale")
+	assert.False(t, ok, "campaign isn't known before reload")
+	assert.NoError(t, r.reload())
+	_, ok = r.Get("sale")
+	assert.True(t, ok, "campaign is known after reload")
+
+	list, err := r.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
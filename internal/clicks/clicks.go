@@ -0,0 +1,133 @@
+package clicks
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/metrics"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+const (
+	defaultBufferSize    = 10000
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+)
+
+type saver interface {
+	SaveClicks(clicks []model.Click) error
+}
+
+// Recorder collects clicks in buffer and saves them to storage by batches in background,
+// so redirect never waits for storage
+type Recorder struct {
+	storage       saver
+	queue         chan model.Click
+	batchSize     int
+	flushInterval time.Duration
+	// mu guards closed, so no click is sent to queue after run has drained it
+	mu     sync.RWMutex
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func New(conf config.Clicks, storage saver) *Recorder {
+	r := &Recorder{
+		storage:       storage,
+		queue:         make(chan model.Click, orDefault(conf.BufferSize, defaultBufferSize)),
+		batchSize:     orDefault(conf.BatchSize, defaultBatchSize),
+		flushInterval: conf.FlushInterval.Duration,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if r.flushInterval <= 0 {
+		r.flushInterval = defaultFlushInterval
+	}
+	go r.run()
+	return r
+}
+
+func orDefault(value, def int) int {
+	if value <= 0 {
+		return def
+	}
+	return value
+}
+
+// Record adds click to buffer, click is dropped if buffer is full or recorder is closed
+func (r *Recorder) Record(click model.Click) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		metrics.ClicksDroppedCounter.Inc()
+		return
+	}
+	select {
+	case r.queue <- click:
+	default:
+		metrics.ClicksDroppedCounter.Inc()
+	}
+}
+
+// Close saves buffered clicks and stops background saving, clicks recorded after it are dropped
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	close(r.stop)
+	<-r.done
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.Click, 0, r.batchSize)
+	for {
+		select {
+		case click := <-r.queue:
+			batch = r.add(batch, click)
+		case <-r.stop:
+			for {
+				select {
+				case click := <-r.queue:
+					batch = r.add(batch, click)
+				default:
+					r.flush(batch)
+					return
+				}
+			}
+		case <-ticker.C:
+			batch = r.flush(batch)
+		}
+	}
+}
+
+func (r *Recorder) add(batch []model.Click, click model.Click) []model.Click {
+	batch = append(batch, click)
+	if len(batch) >= r.batchSize {
+		batch = r.flush(batch)
+	}
+	return batch
+}
+
+func (r *Recorder) flush(batch []model.Click) []model.Click {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := r.storage.SaveClicks(batch); err != nil {
+		log.Errorf("Can't save %v clicks: %+v", len(batch), err)
+		metrics.ClicksDroppedCounter.Add(float64(len(batch)))
+	} else {
+		metrics.ClicksSavedCounter.Add(float64(len(batch)))
+	}
+	return batch[:0]
+}
@@ -0,0 +1,144 @@
+package clicks
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/metrics"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+type testSaver struct {
+	mu      sync.Mutex
+	batches [][]model.Click
+	err     error
+	// block holds saving until it is closed
+	block chan struct{}
+}
+
+func (s *testSaver) SaveClicks(clicks []model.Click) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, append([]model.Click(nil), clicks...))
+	return s.err
+}
+
+func (s *testSaver) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret := make([]int, 0, len(s.batches))
+	for _, batch := range s.batches {
+		ret = append(ret, len(batch))
+	}
+	return ret
+}
+
+func newTestConfig(bufferSize, batchSize int, flushInterval time.Duration) config.Clicks {
+	return config.Clicks{
+		BufferSize:    bufferSize,
+		BatchSize:     batchSize,
+		FlushInterval: model.Duration{Duration: flushInterval},
+	}
+}
+
+func TestRecorder_FlushByBatchSize(t *testing.T) {
+	saver := &testSaver{}
+	r := New(newTestConfig(100, 3, time.Hour), saver)
+	for i := 0; i < 7; i++ {
+		r.Record(model.Click{LinkId: uint64(i)})
+	}
+	assert.Eventually(t, func() bool { return len(saver.sizes()) == 2 }, time.Second, time.Millisecond)
+
+	// the rest is saved on close
+	r.Close()
+	assert.Equal(t, []int{3, 3, 1}, saver.sizes())
+}
+
+func TestRecorder_FlushByInterval(t *testing.T) {
+	saver := &testSaver{}
+	r := New(newTestConfig(100, 100, 10*time.Millisecond), saver)
+	defer r.Close()
+
+	r.Record(model.Click{LinkId: 1})
+	r.Record(model.Click{LinkId: 2})
+	assert.Eventually(t, func() bool {
+		sizes := saver.sizes()
+		return len(sizes) == 1 && sizes[0] == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestRecorder_DropWhenBufferIsFull(t *testing.T) {
+	saver := &testSaver{block: make(chan struct{})}
+	r := New(newTestConfig(2, 1, time.Hour), saver)
+	dropped := testutil.ToFloat64(metrics.ClicksDroppedCounter)
+
+	// the first click is taken by blocked saving, the next two fill buffer
+	r.Record(model.Click{LinkId: 1})
+	assert.Eventually(t, func() bool { return len(r.queue) == 0 }, time.Second, time.Millisecond)
+	for i := 2; i <= 5; i++ {
+		r.Record(model.Click{LinkId: uint64(i)})
+	}
+	assert.Equal(t, dropped+2, testutil.ToFloat64(metrics.ClicksDroppedCounter))
+
+	close(saver.block)
+	r.Close()
+	assert.Equal(t, []int{1, 1, 1}, saver.sizes())
+}
+
+func TestRecorder_DropOnSaveError(t *testing.T) {
+	saver := &testSaver{err: errors.New("broken storage")}
+	r := New(newTestConfig(100, 100, time.Hour), saver)
+	dropped := testutil.ToFloat64(metrics.ClicksDroppedCounter)
+
+	r.Record(model.Click{LinkId: 1})
+	r.Record(model.Click{LinkId: 2})
+	r.Close()
+	assert.Equal(t, dropped+2, testutil.ToFloat64(metrics.ClicksDroppedCounter))
+}
+
+func TestRecorder_RecordAfterClose(t *testing.T) {
+	saver := &testSaver{}
+	r := New(newTestConfig(100, 100, time.Hour), saver)
+	r.Record(model.Click{LinkId: 1})
+	r.Close()
+	dropped := testutil.ToFloat64(metrics.ClicksDroppedCounter)
+
+	assert.NotPanics(t, func() { r.Record(model.Click{LinkId: 2}) })
+	assert.Equal(t, dropped+1, testutil.ToFloat64(metrics.ClicksDroppedCounter))
+	assert.Equal(t, []int{1}, saver.sizes())
+}
+
+func TestRecorder_ConcurrentRecordAndClose(t *testing.T) {
+	saver := &testSaver{}
+	r := New(newTestConfig(10000, 100, time.Hour), saver)
+	saved := testutil.ToFloat64(metrics.ClicksSavedCounter)
+	dropped := testutil.ToFloat64(metrics.ClicksDroppedCounter)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				r.Record(model.Click{LinkId: uint64(i)})
+			}
+		}()
+	}
+	r.Close()
+	wg.Wait()
+
+	// every click is either saved or dropped
+	total := testutil.ToFloat64(metrics.ClicksSavedCounter) - saved + testutil.ToFloat64(metrics.ClicksDroppedCounter) - dropped
+	assert.Equal(t, float64(1000), total)
+}
@@ -10,24 +10,50 @@ import (
 )
 
 type Config struct {
-	LogLevel string `json:"log_level" env:"SHORTENER_LOG_LEVEL"`
-	Server   `json:"server"`
-	Storage  `json:"storage"`
-	Cache    `json:"cache"`
+	LogLevel  string `json:"log_level" env:"SHORTENER_LOG_LEVEL"`
+	Server    `json:"server"`
+	Storage   `json:"storage"`
+	Cache     `json:"cache"`
+	Clicks    `json:"clicks"`
+	Code      `json:"code"`
+	Tokens    `json:"tokens"`
+	Campaigns `json:"campaigns"`
+	RateLimit `json:"rateLimit"`
+	URLPolicy `json:"urlPolicy"`
 }
 
 type Server struct {
-	Port        string         `json:"port" env:"SHORTENER_SERVER_PORT"`
-	Schema      string         `json:"schema" env:"SHORTENER_SERVER_SCHEMA"`
-	Prefix      string         `json:"prefix" env:"SHORTENER_SERVER_PREFIX"`
-	Err404      string         `json:"err404" env:"SHORTENER_SERVER_ERR404"`
-	Token       string         `json:"token" env:"SHORTENER_SERVER_TOKEN"`
-	ReadTimeout model.Duration `json:"readTimeout" env:"SHORTENER_SERVER_READ_TIMEOUT"`
-	IdleTimeout model.Duration `json:"idleTimeout" env:"SHORTENER_SERVER_IDLE_TIMEOUT"`
+	Port         string         `json:"port" env:"SHORTENER_SERVER_PORT"`
+	Schema       string         `json:"schema" env:"SHORTENER_SERVER_SCHEMA"`
+	Prefix       string         `json:"prefix" env:"SHORTENER_SERVER_PREFIX"`
+	Err404       string         `json:"err404" env:"SHORTENER_SERVER_ERR404"`
+	NotActive    string         `json:"notActive" env:"SHORTENER_SERVER_NOT_ACTIVE"`
+	RedirectType int            `json:"redirectType" env:"SHORTENER_SERVER_REDIRECT_TYPE"`
+	Token        string         `json:"token" env:"SHORTENER_SERVER_TOKEN"`
+	ReadTimeout  model.Duration `json:"readTimeout" env:"SHORTENER_SERVER_READ_TIMEOUT"`
+	IdleTimeout  model.Duration `json:"idleTimeout" env:"SHORTENER_SERVER_IDLE_TIMEOUT"`
+	Alias        Alias          `json:"alias"`
+	Query        Query          `json:"query"`
+}
+
+// Query is default policy of forwarding query of request to url of link
+type Query struct {
+	Policy string   `json:"policy" env:"SHORTENER_QUERY_POLICY"`
+	Allow  []string `json:"allow" env:"SHORTENER_QUERY_ALLOW"`
+}
+
+type Alias struct {
+	Charset   string   `json:"charset" env:"SHORTENER_ALIAS_CHARSET"`
+	MinLength int      `json:"minLength" env:"SHORTENER_ALIAS_MIN_LENGTH"`
+	MaxLength int      `json:"maxLength" env:"SHORTENER_ALIAS_MAX_LENGTH"`
+	Reserved  []string `json:"reserved" env:"SHORTENER_ALIAS_RESERVED"`
 }
 
 type Storage struct {
-	Kind  string `json:"kind" env:"SHORTENER_STORAGE_KIND"`
+	Kind string `json:"kind" env:"SHORTENER_STORAGE_KIND"`
+	// ExpiredGrace is period, during which expired links are kept to redirect to their expiredUrl
+	ExpiredGrace model.Duration `json:"expiredGrace" env:"SHORTENER_STORAGE_EXPIRED_GRACE"`
+
 	Redis struct {
 		Host     string `json:"host" env:"SHORTENER_REDIS_HOST"`
 		Port     int    `json:"port" env:"SHORTENER_REDIS_PORT"`
@@ -47,10 +73,73 @@ type Storage struct {
 		Bucket  string `json:"bucket" env:"SHORTENER_BOLT_BUCKET"`
 		Timeout string `json:"timeout" env:"SHORTENER_BOLT_TIMEOUT"`
 	} `json:"bolt"`
+	Sqlite struct {
+		Path    string         `json:"path" env:"SHORTENER_SQLITE_PATH"`
+		Timeout model.Duration `json:"timeout" env:"SHORTENER_SQLITE_TIMEOUT"`
+	} `json:"sqlite"`
+	Memory struct {
+		Path string `json:"path" env:"SHORTENER_MEMORY_PATH"`
+	} `json:"memory"`
 }
 
 type Cache struct {
 	Size int `json:"size" env:"SHORTENER_CACHE_SIZE"`
+	// TTL is maximum time of keeping link in cache, zero is unlimited
+	TTL model.Duration `json:"ttl" env:"SHORTENER_CACHE_TTL"`
+}
+
+type Code struct {
+	// Length is target length of generated codes, zero uses whole uint64 range
+	Length int `json:"length" env:"SHORTENER_CODE_LENGTH"`
+	// Alphabet of codes, default is base62
+	Alphabet        string `json:"alphabet" env:"SHORTENER_CODE_ALPHABET"`
+	CaseInsensitive bool   `json:"caseInsensitive" env:"SHORTENER_CODE_CASE_INSENSITIVE"`
+	// Strategy of ids is random or sequential
+	Strategy string `json:"strategy" env:"SHORTENER_CODE_STRATEGY"`
+	// Key of permutation of sequential ids
+	Key string `json:"key" env:"SHORTENER_CODE_KEY"`
+}
+
+type Tokens struct {
+	// RefreshInterval is period of reloading tokens from storage
+	RefreshInterval model.Duration `json:"refreshInterval" env:"SHORTENER_TOKENS_REFRESH_INTERVAL"`
+}
+
+type Campaigns struct {
+	// RefreshInterval is period of reloading campaigns from storage
+	RefreshInterval model.Duration `json:"refreshInterval" env:"SHORTENER_CAMPAIGNS_REFRESH_INTERVAL"`
+}
+
+type RateLimit struct {
+	// Create limits link creation per api token
+	Create Limit `json:"create" envPrefix:"SHORTENER_RATE_LIMIT_CREATE_"`
+	// Redirect limits redirects per client ip
+	Redirect Limit `json:"redirect" envPrefix:"SHORTENER_RATE_LIMIT_REDIRECT_"`
+	// Password limits password attempts per protected link
+	Password Limit `json:"password" envPrefix:"SHORTENER_RATE_LIMIT_PASSWORD_"`
+}
+
+// Limit is token bucket, which is refilled by Rate tokens per second up to Burst, zero rate is no limit
+type Limit struct {
+	Rate  float64 `json:"rate" env:"RATE"`
+	Burst int     `json:"burst" env:"BURST"`
+}
+
+// URLPolicy restricts destination urls of links
+type URLPolicy struct {
+	Schemes           []string `json:"schemes" env:"SHORTENER_URL_POLICY_SCHEMES"`
+	MaxLength         int      `json:"maxLength" env:"SHORTENER_URL_POLICY_MAX_LENGTH"`
+	BlockIpHosts      bool     `json:"blockIpHosts" env:"SHORTENER_URL_POLICY_BLOCK_IP_HOSTS"`
+	BlockPrivateHosts bool     `json:"blockPrivateHosts" env:"SHORTENER_URL_POLICY_BLOCK_PRIVATE_HOSTS"`
+	// DomainsFile is json file with allow and block lists of domain patterns, it is reloaded on change
+	DomainsFile    string         `json:"domainsFile" env:"SHORTENER_URL_POLICY_DOMAINS_FILE"`
+	ReloadInterval model.Duration `json:"reloadInterval" env:"SHORTENER_URL_POLICY_RELOAD_INTERVAL"`
+}
+
+type Clicks struct {
+	BufferSize    int            `json:"bufferSize" env:"SHORTENER_CLICKS_BUFFER_SIZE"`
+	BatchSize     int            `json:"batchSize" env:"SHORTENER_CLICKS_BATCH_SIZE"`
+	FlushInterval model.Duration `json:"flushInterval" env:"SHORTENER_CLICKS_FLUSH_INTERVAL"`
 }
 
 func FromFileAndEnv(mainPath string, extraPath ...string) (*Config, error) {
@@ -0,0 +1,57 @@
+package feistel
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+)
+
+const rounds = 4
+
+// Permutation is keyed bijection of range [0, size), it is balanced Feistel network
+// over the smallest even bit width covering the range, out of range values are cycle walked
+type Permutation struct {
+	keys [rounds]uint64
+}
+
+func New(key string) *Permutation {
+	sum := sha256.Sum256([]byte(key))
+	p := &Permutation{}
+	for i := range p.keys {
+		p.keys[i] = binary.BigEndian.Uint64(sum[i*8:])
+	}
+	return p
+}
+
+// Permute maps value of range [0, size) to another value of the same range
+func (p *Permutation) Permute(value, size uint64) uint64 {
+	half := (bits.Len64(size-1) + 1) / 2
+	if half == 0 {
+		return value
+	}
+	for {
+		value = p.encrypt(value, half)
+		if value < size {
+			return value
+		}
+	}
+}
+
+func (p *Permutation) encrypt(value uint64, half int) uint64 {
+	mask := uint64(1)<<half - 1
+	left, right := value>>half, value&mask
+	for _, key := range p.keys {
+		left, right = right, left^(mix(right^key)&mask)
+	}
+	return left<<half | right
+}
+
+// mix is finalizer of splitmix64
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
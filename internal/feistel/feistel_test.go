@@ -0,0 +1,48 @@
+package feistel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermutation_Bijection(t *testing.T) {
+	// odd bit widths and sizes, which are not powers of two, are cycle walked
+	sizes := []uint64{1, 2, 3, 4, 5, 7, 16, 17, 62, 100, 1000, 3782, 4096, 4097}
+	for _, key := range []string{"", "secret"} {
+		p := New(key)
+		for _, size := range sizes {
+			seen := make([]bool, size)
+			for value := uint64(0); value < size; value++ {
+				permuted := p.Permute(value, size)
+				if !assert.Less(t, permuted, size, "key %q size %v value %v", key, size, value) {
+					break
+				}
+				if !assert.False(t, seen[permuted], "key %q size %v duplicate %v", key, size, permuted) {
+					break
+				}
+				seen[permuted] = true
+			}
+		}
+	}
+}
+
+func TestPermutation_Key(t *testing.T) {
+	const size = 1000
+	permute := func(p *Permutation) []uint64 {
+		ret := make([]uint64, size)
+		for value := range ret {
+			ret[value] = p.Permute(uint64(value), size)
+		}
+		return ret
+	}
+	first := permute(New("first"))
+	assert.Equal(t, first, permute(New("first")), "permutation is deterministic")
+	assert.NotEqual(t, first, permute(New("second")), "permutation depends on key")
+
+	identity := make([]uint64, size)
+	for value := range identity {
+		identity[value] = uint64(value)
+	}
+	assert.NotEqual(t, identity, first, "values are shuffled")
+}
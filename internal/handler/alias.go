@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+)
+
+const (
+	defaultAliasCharset   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
+	defaultAliasMinLength = 1
+	defaultAliasMaxLength = 64
+)
+
+// routeNames are first path segments of handler routes, they are always reserved
+var routeNames = []string{"health", "metrics", "api"}
+
+type aliasPolicy struct {
+	charset   string
+	minLength int
+	maxLength int
+	reserved  map[string]struct{}
+}
+
+func newAliasPolicy(conf config.Alias) aliasPolicy {
+	p := aliasPolicy{
+		charset:   conf.Charset,
+		minLength: conf.MinLength,
+		maxLength: conf.MaxLength,
+		reserved:  make(map[string]struct{}),
+	}
+	if p.charset == "" {
+		p.charset = defaultAliasCharset
+	}
+	if p.minLength <= 0 {
+		p.minLength = defaultAliasMinLength
+	}
+	if p.maxLength <= 0 {
+		p.maxLength = defaultAliasMaxLength
+	}
+	for _, word := range append(routeNames, conf.Reserved...) {
+		p.reserved[strings.ToLower(word)] = struct{}{}
+	}
+	return p
+}
+
+func (p aliasPolicy) validate(alias string) error {
+	if !p.fitsLength(alias) {
+		return errors.New(fmt.Sprintf("Alias length must be from %v to %v", p.minLength, p.maxLength))
+	}
+	if symbol, ok := p.invalidSymbol(alias); ok {
+		return errors.New(fmt.Sprintf("Alias contains invalid character: %q", symbol))
+	}
+	if _, ok := p.reserved[strings.ToLower(alias)]; ok {
+		return errors.New("Alias is reserved: " + alias)
+	}
+	return nil
+}
+
+// mayBeAlias reports whether code has length and characters of alias, so it may be taken by alias
+func (p aliasPolicy) mayBeAlias(code string) bool {
+	if !p.fitsLength(code) {
+		return false
+	}
+	_, ok := p.invalidSymbol(code)
+	return !ok
+}
+
+func (p aliasPolicy) fitsLength(alias string) bool {
+	return len(alias) >= p.minLength && len(alias) <= p.maxLength
+}
+
+func (p aliasPolicy) invalidSymbol(alias string) (rune, bool) {
+	for _, symbol := range alias {
+		if !strings.ContainsRune(p.charset, symbol) {
+			return symbol, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+)
+
+func TestAliasPolicy(t *testing.T) {
+	p := newAliasPolicy(config.Alias{Charset: "abc-", MinLength: 2, MaxLength: 4, Reserved: []string{"Ab"}})
+	tests := []struct {
+		alias      string
+		valid      bool
+		mayBeAlias bool
+	}{
+		{alias: "ab-c", valid: true, mayBeAlias: true},
+		{alias: "a", valid: false, mayBeAlias: false},
+		{alias: "abcab", valid: false, mayBeAlias: false},
+		{alias: "abd", valid: false, mayBeAlias: false},
+		// reserved words are checked case insensitively, but codes equal to them still may be aliases
+		{alias: "ab", valid: false, mayBeAlias: true},
+		{alias: "api", valid: false, mayBeAlias: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			assert.Equal(t, tt.valid, p.validate(tt.alias) == nil)
+			assert.Equal(t, tt.mayBeAlias, p.mayBeAlias(tt.alias))
+		})
+	}
+}
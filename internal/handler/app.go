@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/bluele/gcache"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/auth"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/base62"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/campaign"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/clicks"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/storage"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/urlpolicy"
+)
+
+// App is handler with services it is built from
+type App struct {
+	Handler   http.Handler
+	storage   *storage.Storage
+	tokens    *auth.Registry
+	campaigns *campaign.Registry
+	recorder  *clicks.Recorder
+	urls      *urlpolicy.Policy
+}
+
+// NewApp connects storage and starts services of handler by configuration
+func NewApp(conf *config.Config) (*App, error) {
+	codec, err := base62.NewCodec(conf.Code.Alphabet, conf.Code.Length, conf.Code.CaseInsensitive)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't create code codec")
+	}
+
+	a := &App{}
+	ok := false
+	defer func() {
+		if !ok {
+			a.Close()
+		}
+	}()
+
+	// connect to storage service
+	a.storage, err = storage.New(conf.Storage, codec)
+	if err != nil {
+		return nil, err
+	}
+	switch conf.Code.Strategy {
+	case "", "random":
+	case "sequential":
+		if conf.Code.Key == "" {
+			return nil, errors.New("Code key is required for sequential strategy")
+		}
+		a.storage.SetIDGenerator(storage.NewSequentialGenerator(a.storage, codec, conf.Code.Key))
+	default:
+		return nil, errors.Errorf("Unknown code strategy %v", conf.Code.Strategy)
+	}
+	log.Infof("Code strategy: %v, length: %v", conf.Code.Strategy, conf.Code.Length)
+
+	a.tokens, err = auth.New(conf.Server.Token, a.storage, conf.Tokens.RefreshInterval.Duration)
+	if err != nil {
+		return nil, err
+	}
+	a.campaigns, err = campaign.New(a.storage, conf.Campaigns.RefreshInterval.Duration)
+	if err != nil {
+		return nil, err
+	}
+	a.recorder = clicks.New(conf.Clicks, a.storage)
+	a.urls, err = urlpolicy.New(conf.URLPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := gcache.New(conf.Cache.Size).ARC().Build()
+	log.Infof("Cache size: %v, ttl: %v", conf.Cache.Size, conf.Cache.TTL.Duration)
+
+	a.Handler = New(conf.Server, conf.RateLimit, a.storage, cache, conf.Cache.TTL.Duration, codec, a.tokens, a.recorder, a.urls, a.campaigns)
+	ok = true
+	return a, nil
+}
+
+// Close stops services and closes storage, it must be called after shutdown of server,
+// because handlers of accepted requests may still record clicks
+func (a *App) Close() {
+	if a.recorder != nil {
+		a.recorder.Close()
+	}
+	if a.tokens != nil {
+		a.tokens.Close()
+	}
+	if a.campaigns != nil {
+		a.campaigns.Close()
+	}
+	if a.urls != nil {
+		a.urls.Close()
+	}
+	if a.storage != nil {
+		_ = a.storage.Close()
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/auth"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+const (
+	maxBatchSize = 1000
+	// maxBatchBodySize is size of body of batch request, which fits maxBatchSize items with long urls
+	maxBatchBodySize = maxBatchSize * 8 << 10
+)
+
+// batchResult is result of batch item, either code of link or error
+type batchResult struct {
+	Code     string `json:"code,omitempty"`
+	ShortURL string `json:"shortUrl,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// readBatchBody reads body of batch request, body larger than maxBatchBodySize is rejected with 413
+func readBatchBody(w http.ResponseWriter, r *http.Request) ([]byte, int, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, http.StatusRequestEntityTooLarge, errors.Errorf("Batch body must not exceed %v bytes", maxBatchBodySize)
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Can't read body of request")
+	}
+	return body, http.StatusOK, nil
+}
+
+// createBatch creates links by array of create requests and returns results in the same order,
+// invalid items don't prevent creation of others
+func (h *handler) createBatch(r *http.Request) (interface{}, int, error) {
+	startAt := time.Now()
+	token, err := h.authorize(r, auth.ScopeCreate)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	var requests []createRequest
+	// response writer isn't available here, body is already limited by batchRateLimit
+	body, status, err := readBatchBody(nil, r)
+	if err != nil {
+		return nil, status, err
+	}
+	if err := json.Unmarshal(body, &requests); err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "Unable to info JSON request body")
+	}
+	if len(requests) == 0 || len(requests) > maxBatchSize {
+		return nil, http.StatusBadRequest, errors.New(fmt.Sprintf("Batch size must be from 1 to %v", maxBatchSize))
+	}
+
+	results := make([]batchResult, len(requests))
+	batch := make([]model.BatchItem, 0, len(requests))
+	// positions of batch items in results
+	positions := make([]int, 0, len(requests))
+	for i, request := range requests {
+		item, status, err := h.newItem(request, token)
+		if err != nil {
+			if status == http.StatusInternalServerError {
+				return nil, status, err
+			}
+			results[i].Error = err.Error()
+			continue
+		}
+		batch = append(batch, item)
+		positions = append(positions, i)
+	}
+
+	startStorageAt := time.Now()
+	saved, err := h.storage.SaveBatch(batch)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Create batch handler error")
+	}
+	durationStorage := time.Since(startStorageAt)
+
+	created := 0
+	for j, result := range saved {
+		i := positions[j]
+		if result.Err != nil {
+			results[i].Error = result.Err.Error()
+			continue
+		}
+		results[i].Code = result.Code
+		results[i].ShortURL = h.shortUrl(result.Code)
+		created++
+	}
+
+	log.Infof("Generated batch of links: %v of %v, duration: %v, storage: %v",
+		created, len(requests), time.Since(startAt), durationStorage)
+
+	return results, http.StatusOK, nil
+}
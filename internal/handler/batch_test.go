@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+)
+
+func TestCreateBatch_BodySize(t *testing.T) {
+	endpoint := startTestServer(t, config.Server{})
+
+	item := `{"url": "http://example.com/` + strings.Repeat("a", 1000) + `"},`
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+	}{
+		{name: "small", token: testToken, body: `[{"url": "http://example.com/"}]`, status: http.StatusOK},
+		{name: "too large", token: testToken, body: "[" + strings.Repeat(item, maxBatchBodySize/len(item)+1) + "]", status: http.StatusRequestEntityTooLarge},
+		// body is limited before authorization
+		{name: "too large without token", body: strings.Repeat(" ", maxBatchBodySize+1), status: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, endpoint+"/api/v1/links:batch", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-Token", tt.token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/auth"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/campaign"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+type campaignResponse struct {
+	Name    string            `json:"name"`
+	Params  map[string]string `json:"params"`
+	Created time.Time         `json:"created"`
+}
+
+type saveCampaignRequest struct {
+	Params map[string]string `json:"params"`
+}
+
+func (h *handler) campaignRoutes(r chi.Router) {
+	r.Get("/", responseHandler(h.listCampaigns))
+	r.Put("/{name}", responseHandler(h.saveCampaign))
+	r.Delete("/{name}", responseHandler(h.deleteCampaign))
+	r.Get("/{name}/stats", responseHandler(h.campaignStats))
+}
+
+func newCampaignResponse(campaign model.Campaign) campaignResponse {
+	return campaignResponse{
+		Name:    campaign.Name,
+		Params:  campaign.Params,
+		Created: campaign.Created,
+	}
+}
+
+// parseCampaign validates campaign of request, empty name removes campaign from link
+func (h *handler) parseCampaign(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	if _, ok := h.campaigns.Get(name); !ok {
+		return "", errors.Errorf("Unknown campaign %v", name)
+	}
+	return name, nil
+}
+
+// applyCampaign sets parameters of campaign to query of uri, they replace parameters with the same name,
+// uri is kept as is if campaign is deleted
+func (h *handler) applyCampaign(uri string, name string) string {
+	if name == "" {
+		return uri
+	}
+	campaign, ok := h.campaigns.Get(name)
+	if !ok {
+		return uri
+	}
+	target, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	values := target.Query()
+	for param, value := range campaign.Params {
+		values.Set(param, value)
+	}
+	target.RawQuery = values.Encode()
+	return target.String()
+}
+
+func (h *handler) listCampaigns(r *http.Request) (interface{}, int, error) {
+	if _, err := h.authorize(r, auth.ScopeRead); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	campaigns, err := h.campaigns.List()
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "List campaigns handler error")
+	}
+	response := make([]campaignResponse, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		response = append(response, newCampaignResponse(campaign))
+	}
+	return response, http.StatusOK, nil
+}
+
+// saveCampaign creates or replaces campaign, new parameters are applied to redirects of its links at once
+func (h *handler) saveCampaign(r *http.Request) (interface{}, int, error) {
+	token, err := h.authorize(r, auth.ScopeManage)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	var request saveCampaignRequest
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Can't read body of request")
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "Unable to info JSON request body")
+	}
+	name := chi.URLParam(r, "name")
+	if err := campaign.Validate(name, request.Params); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	saved, err := h.campaigns.Save(name, request.Params)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Save campaign handler error")
+	}
+
+	log.Infof("Campaign %v saved by %v", name, token.Name)
+	return newCampaignResponse(saved), http.StatusOK, nil
+}
+
+func (h *handler) deleteCampaign(r *http.Request) (interface{}, int, error) {
+	token, err := h.authorize(r, auth.ScopeManage)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	name := chi.URLParam(r, "name")
+	if err := h.campaigns.Delete(name); err != nil {
+		if errors.Is(err, model.ErrNoCampaign) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Delete campaign handler error")
+	}
+
+	log.Infof("Campaign %v deleted by %v", name, token.Name)
+	return name, http.StatusOK, nil
+}
+
+// campaignStats sums clicks of links of campaign, links of other owners are counted only for admin
+func (h *handler) campaignStats(r *http.Request) (interface{}, int, error) {
+	token, err := h.authorize(r, auth.ScopeRead)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	query := model.ListQuery{Campaign: chi.URLParam(r, "name"), Owner: token.Name}
+	if auth.HasScope(token, auth.ScopeAdmin) {
+		query.Owner = r.URL.Query().Get("owner")
+	}
+	stat, err := h.storage.CampaignClickStat(query)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Campaign stats handler error")
+	}
+	return stat, http.StatusOK, nil
+}
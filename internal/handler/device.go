@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/useragent"
+)
+
+// parseDeviceRules validates device rules of request, every device may have one rule, empty list removes rules
+func (h *handler) parseDeviceRules(rules []model.DeviceRule) ([]model.DeviceRule, int, error) {
+	if len(rules) == 0 {
+		return nil, http.StatusOK, nil
+	}
+	ret := make([]model.DeviceRule, 0, len(rules))
+	for _, rule := range rules {
+		if !slices.Contains(useragent.Devices, rule.Device) {
+			return nil, http.StatusBadRequest, errors.Errorf("Unknown device %v, it must be ios, android, desktop or bot", rule.Device)
+		}
+		if slices.ContainsFunc(ret, func(other model.DeviceRule) bool { return other.Device == rule.Device }) {
+			return nil, http.StatusBadRequest, errors.Errorf("Device %v has several rules", rule.Device)
+		}
+		uri, err := parseURL(rule.URL)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.Errorf("Invalid url of device %v", rule.Device)
+		}
+		if err := h.urls.Check(uri); err != nil {
+			return nil, http.StatusUnprocessableEntity, err
+		}
+		ret = append(ret, model.DeviceRule{Device: rule.Device, URL: uri.String()})
+	}
+	return ret, http.StatusOK, nil
+}
+
+// routeDevice returns url of rule for device of user agent, url of link is the fallback
+func routeDevice(link cachedLink, userAgent string) string {
+	if len(link.deviceRules) == 0 {
+		return link.uri
+	}
+	device := useragent.Classify(userAgent)
+	for _, rule := range link.deviceRules {
+		if rule.Device == device {
+			return rule.URL
+		}
+	}
+	return link.uri
+}
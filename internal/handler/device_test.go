@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/useragent"
+)
+
+const (
+	iphoneUserAgent  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	ipadUserAgent    = "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1"
+	androidUserAgent = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+	desktopUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	botUserAgent     = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+)
+
+func TestRouteDevice(t *testing.T) {
+	link := cachedLink{
+		uri: "https://example.com/",
+		deviceRules: []model.DeviceRule{
+			{Device: useragent.DeviceIOS, URL: "https://apps.apple.com/app/id1"},
+			{Device: useragent.DeviceAndroid, URL: "https://play.google.com/store/apps/details?id=app"},
+			{Device: useragent.DeviceBot, URL: "https://example.com/preview"},
+		},
+	}
+	tests := []struct {
+		name      string
+		link      cachedLink
+		userAgent string
+		want      string
+	}{
+		{name: "iphone", link: link, userAgent: iphoneUserAgent, want: "https://apps.apple.com/app/id1"},
+		{name: "ipad", link: link, userAgent: ipadUserAgent, want: "https://apps.apple.com/app/id1"},
+		{name: "android", link: link, userAgent: androidUserAgent, want: "https://play.google.com/store/apps/details?id=app"},
+		{name: "bot", link: link, userAgent: botUserAgent, want: "https://example.com/preview"},
+		{name: "device without rule", link: link, userAgent: desktopUserAgent, want: "https://example.com/"},
+		{name: "unknown device", link: link, userAgent: "", want: "https://example.com/"},
+		{name: "link without rules", link: cachedLink{uri: "https://example.com/"}, userAgent: iphoneUserAgent, want: "https://example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, routeDevice(tt.link, tt.userAgent))
+		})
+	}
+}
+
+func TestRedirect_Device(t *testing.T) {
+	endpoint := startTestServer(t, config.Server{})
+	code := createTestLink(t, endpoint, `{
+		"url": "https://example.com/?a=1",
+		"deviceRules": [
+			{"device": "ios", "url": "https://apps.apple.com/app/id1"},
+			{"device": "desktop", "url": "https://example.com/desktop"}
+		]
+	}`)
+
+	tests := []struct {
+		name      string
+		userAgent string
+		query     string
+		want      string
+	}{
+		{name: "ios", userAgent: iphoneUserAgent, want: "https://apps.apple.com/app/id1"},
+		{name: "desktop", userAgent: desktopUserAgent, want: "https://example.com/desktop"},
+		{name: "fallback", userAgent: androidUserAgent, want: "https://example.com/?a=1"},
+		{name: "ios with query", userAgent: iphoneUserAgent, query: "?b=2", want: "https://apps.apple.com/app/id1?b=2"},
+		{name: "fallback with query", userAgent: botUserAgent, query: "?b=2", want: "https://example.com/?a=1&b=2"},
+	}
+	// link is loaded from storage by the first request only, rules are matched on cached link then
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, redirectLocation(t, endpoint, "/"+code+tt.query, tt.userAgent))
+		})
+	}
+}
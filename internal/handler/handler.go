@@ -8,40 +8,80 @@ import (
 	"net/http"
 	"net/url"
 	"runtime"
-	"strings"
 	"time"
 
 	"github.com/bluele/gcache"
 	logger "github.com/chi-middleware/logrus-logger"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/auth"
 	"github.com/sergiusd/go-scanty-url-shortener/internal/base62"
 	"github.com/sergiusd/go-scanty-url-shortener/internal/metrics"
 	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/ratelimit"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type IService interface {
-	Save(string, *time.Time, bool) (string, error)
-	Load(id uint64) (string, error)
+	Save(item model.Item, tryFindExists bool) (string, error)
+	SaveBatch(batch []model.BatchItem) ([]model.BatchResult, error)
+	Load(id uint64) (model.Item, error)
+	LoadByAlias(alias string) (model.Item, error)
+	Get(id uint64) (model.Item, error)
+	FindAlias(alias string) (uint64, error)
+	SetAliasMatcher(mayBeAlias func(code string) bool)
+	Update(item model.Item) error
+	Visit(id uint64) error
+	Delete(id uint64) error
+	List(query model.ListQuery) ([]model.Item, error)
+	ClickStat(id uint64) (model.ClickStat, error)
+	CampaignClickStat(query model.ListQuery) (model.ClickStat, error)
 	Close() error
 	Stat(ctx context.Context) (any, error)
 }
 
 type ICache interface {
 	Set(key, value any) error
+	SetWithExpire(key, value any, expiration time.Duration) error
 	Get(key any) (any, error)
+	Remove(key any) bool
 	HitRate() float64
 	HitCount() uint64
 	MissCount() uint64
 	LookupCount() uint64
 }
 
-func New(conf config.Server, storage IService, cache ICache) http.Handler {
+type IAuth interface {
+	Authenticate(secret string) (model.Token, bool)
+	Issue(name string, scopes []string) (model.Token, string, error)
+	Revoke(name string) error
+	List() ([]model.Token, error)
+}
+
+type ICampaigns interface {
+	Get(name string) (model.Campaign, bool)
+	Save(name string, params map[string]string) (model.Campaign, error)
+	Delete(name string) error
+	List() ([]model.Campaign, error)
+}
+
+type IRecorder interface {
+	Record(click model.Click)
+}
+
+type IURLPolicy interface {
+	Check(u *url.URL) error
+}
+
+func New(
+	conf config.Server, limits config.RateLimit, storage IService, cache ICache, cacheTTL time.Duration, codec *base62.Codec,
+	tokens IAuth, clicks IRecorder, urls IURLPolicy, campaigns ICampaigns,
+) http.Handler {
 	r := chi.NewRouter()
 
 	routerLogger := log.New()
@@ -54,27 +94,77 @@ func New(conf config.Server, storage IService, cache ICache) http.Handler {
 
 	prometheusHandler := promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
 
+	redirectType := conf.RedirectType
+	if redirectType == 0 {
+		redirectType = http.StatusMovedPermanently
+	}
+	if !isRedirectType(redirectType) {
+		log.Fatalf("Unknown redirect type %v", conf.RedirectType)
+	}
+	queryPolicy := conf.Query.Policy
+	if queryPolicy == "" {
+		queryPolicy = queryAppend
+	}
+	if !isQueryPolicy(queryPolicy) {
+		log.Fatalf("Unknown query policy %v", conf.Query.Policy)
+	}
+
 	h := handler{
-		schema:  conf.Schema,
-		host:    conf.Prefix,
-		err404:  conf.Err404,
-		storage: storage,
-		token:   conf.Token,
-		cache:   cache,
+		schema:          conf.Schema,
+		host:            conf.Prefix,
+		err404:          conf.Err404,
+		notActive:       conf.NotActive,
+		redirectType:    redirectType,
+		storage:         storage,
+		tokens:          tokens,
+		cache:           cache,
+		cacheTTL:        cacheTTL,
+		codec:           codec,
+		clicks:          clicks,
+		urls:            urls,
+		alias:           newAliasPolicy(conf.Alias),
+		passwordLimiter: ratelimit.New(limits.Password),
+		queryPolicy:     queryPolicy,
+		queryAllow:      conf.Query.Allow,
+		campaigns:       campaigns,
 	}
+	storage.SetAliasMatcher(h.alias.mayBeAlias)
 	r.Get("/health", h.health)
 	r.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
 		prometheusHandler.ServeHTTP(w, r)
 	})
-	r.Post("/", responseHandler(h.create))
-	r.Get("/{shortLink}", h.redirect)
+	createLimiter := ratelimit.New(limits.Create)
+	createLimit := rateLimit(createLimiter, h.tokenOrIp, metrics.RateLimitedCreateCounter, rejectApi)
+	createBatchLimit := batchRateLimit(createLimiter, h.tokenOrIp, metrics.RateLimitedCreateCounter, rejectApi)
+	r.With(createLimit).Post("/", responseHandler(h.create))
+	r.With(createBatchLimit).Post("/api/v1/links:batch", responseHandler(h.createBatch))
+	r.Route("/api/v1/links", h.linkRoutes)
+	r.Route("/api/v1/tokens", h.tokenRoutes)
+	r.Route("/api/v1/campaigns", h.campaignRoutes)
+	redirectLimit := rateLimit(ratelimit.New(limits.Redirect), clientIp, metrics.RateLimitedRedirectCounter, h.rejectRedirect)
+	r.With(redirectLimit).Get("/{shortLink}", h.redirect)
+	r.With(redirectLimit).Post("/{shortLink}", h.unlock)
 	return r
 }
 
 type createRequest struct {
 	URL           string  `json:"url"`
+	Alias         *string `json:"alias"`
 	TryFindExists *bool   `json:"tryFindExists"`
-	Expires       *string `json:"expires"`
+	Password      *string `json:"password"`
+	MaxVisits     *int64  `json:"maxVisits"`
+	// ExpiredURL replaces url after expiration
+	ExpiredURL *string `json:"expiredUrl"`
+	// RedirectType is status code of redirect, zero is the default of server
+	RedirectType *int `json:"redirectType"`
+	// QueryPolicy and QueryAllow define forwarding of query of request, empty values are the defaults of server
+	QueryPolicy *string  `json:"queryPolicy"`
+	QueryAllow  []string `json:"queryAllow"`
+	// Campaign is name of campaign, which parameters are added to url on redirect
+	Campaign *string `json:"campaign"`
+	// DeviceRules replace url for clients of their devices
+	DeviceRules []model.DeviceRule `json:"deviceRules"`
+	scheduleRequest
 }
 
 type response struct {
@@ -83,12 +173,66 @@ type response struct {
 }
 
 type handler struct {
-	schema  string
-	host    string
-	err404  string
-	storage IService
-	token   string
-	cache   ICache
+	schema    string
+	host      string
+	err404    string
+	notActive string
+	storage   IService
+	tokens    IAuth
+	cache     ICache
+	cacheTTL  time.Duration
+	codec     *base62.Codec
+	clicks    IRecorder
+	urls      IURLPolicy
+	alias     aliasPolicy
+	// passwordLimiter throttles password attempts per link
+	passwordLimiter *ratelimit.Limiter
+	// redirectType is status of redirect of links without own redirect type
+	redirectType int
+	// queryPolicy and queryAllow are forwarding of query for links without own ones
+	queryPolicy string
+	queryAllow  []string
+	campaigns   ICampaigns
+}
+
+// cachedLink is value of redirect cache, protected links and links with visit limit are never cached
+type cachedLink struct {
+	id           uint64
+	alias        string
+	uri          string
+	expires      *time.Time
+	passwordHash string
+	maxVisits    int64
+	activeFrom   *time.Time
+	expiredUrl   string
+	redirectType int
+	queryPolicy  string
+	queryAllow   []string
+	campaign     string
+	deviceRules  []model.DeviceRule
+}
+
+func newCachedLink(item model.Item) cachedLink {
+	return cachedLink{
+		id:           item.Id,
+		alias:        item.Alias,
+		uri:          item.URL,
+		expires:      item.Expires,
+		passwordHash: item.PasswordHash,
+		maxVisits:    item.MaxVisits,
+		activeFrom:   item.ActiveFrom,
+		expiredUrl:   item.ExpiredURL,
+		redirectType: item.RedirectType,
+		queryPolicy:  item.QueryPolicy,
+		queryAllow:   item.QueryAllow,
+		campaign:     item.Campaign,
+		deviceRules:  item.DeviceRules,
+	}
+}
+
+// isCacheable reports whether link may be redirected from cache without storage
+func (l cachedLink) isCacheable() bool {
+	return l.passwordHash == "" && l.maxVisits == 0
 }
 
 type health struct {
@@ -166,9 +310,9 @@ func (h *handler) create(r *http.Request) (interface{}, int, error) {
 	defer metricStop()
 
 	startAt := time.Now()
-	token := r.Header.Get("X-Token")
-	if token != h.token {
-		return nil, http.StatusForbidden, errors.New("Access denied")
+	token, err := h.authorize(r, auth.ScopeCreate)
+	if err != nil {
+		return nil, http.StatusForbidden, err
 	}
 
 	var request createRequest
@@ -180,47 +324,119 @@ func (h *handler) create(r *http.Request) (interface{}, int, error) {
 		return nil, http.StatusBadRequest, errors.Wrap(err, "Unable to info JSON request body")
 	}
 
-	uri, err := url.ParseRequestURI(request.URL)
+	item, status, err := h.newItem(request, token)
+	if err != nil {
+		return nil, status, err
+	}
+
+	startStorageAt := time.Now()
+	c, err := h.storage.Save(item.Item, item.TryFindExists)
+	if err != nil {
+		if errors.Is(err, model.ErrItemDuplicated) {
+			return nil, http.StatusConflict, err
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Create handler error")
+	}
+	durationStorage := time.Since(startStorageAt)
+
+	shortUrl := h.shortUrl(c)
+
+	duration := time.Since(startAt)
+	action := "Generated link"
+	if item.TryFindExists {
+		action = "Try find or generated link"
+	}
+	log.Infof("%v: %v, duration: %v, storage: %v", shortUrl, action, duration, durationStorage)
 
+	return shortUrl, http.StatusCreated, nil
+}
+
+// newItem validates create request and returns item to save, status is the error status
+func (h *handler) newItem(request createRequest, token model.Token) (model.BatchItem, int, error) {
+	uri, err := parseURL(request.URL)
 	if err != nil {
-		return nil, http.StatusBadRequest, errors.New("Invalid url")
+		return model.BatchItem{}, http.StatusBadRequest, errors.New("Invalid url")
+	}
+	if err := h.urls.Check(uri); err != nil {
+		return model.BatchItem{}, http.StatusUnprocessableEntity, err
+	}
+
+	item := model.Item{URL: uri.String(), Owner: token.Name}
+	if err := request.scheduleRequest.apply(&item); err != nil {
+		return model.BatchItem{}, http.StatusBadRequest, err
+	}
+
+	if request.Alias != nil {
+		item.Alias = *request.Alias
+		if err := h.alias.validate(item.Alias); err != nil {
+			return model.BatchItem{}, http.StatusBadRequest, err
+		}
 	}
 
-	var expires *time.Time
-	if request.Expires != nil {
-		exp, err := time.Parse(time.RFC3339, *request.Expires)
+	if request.Password != nil && *request.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(*request.Password), bcrypt.DefaultCost)
 		if err != nil {
-			return nil, http.StatusBadRequest, errors.New("Invalid expiration date")
+			if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+				return model.BatchItem{}, http.StatusBadRequest, errors.New("Password must be at most 72 bytes")
+			}
+			return model.BatchItem{}, http.StatusInternalServerError, errors.Wrap(err, "Can't hash password")
 		}
-		expires = &exp
+		item.PasswordHash = string(hash)
 	}
 
-	var tryFindExists bool
-	if request.TryFindExists != nil {
-		tryFindExists = *request.TryFindExists
+	if request.MaxVisits != nil {
+		item.MaxVisits = *request.MaxVisits
+		if item.MaxVisits <= 0 {
+			return model.BatchItem{}, http.StatusBadRequest, errors.New("Max visits must be positive")
+		}
 	}
 
-	startStorageAt := time.Now()
-	c, err := h.storage.Save(uri.String(), expires, tryFindExists)
+	if request.ExpiredURL != nil {
+		expiredUrl, status, err := h.parseExpiredURL(*request.ExpiredURL)
+		if err != nil {
+			return model.BatchItem{}, status, err
+		}
+		item.ExpiredURL = expiredUrl
+	}
+
+	if request.RedirectType != nil {
+		redirectType, err := parseRedirectType(*request.RedirectType)
+		if err != nil {
+			return model.BatchItem{}, http.StatusBadRequest, err
+		}
+		item.RedirectType = redirectType
+	}
+
+	if request.QueryPolicy != nil {
+		item.QueryPolicy, err = parseQueryPolicy(*request.QueryPolicy)
+		if err != nil {
+			return model.BatchItem{}, http.StatusBadRequest, err
+		}
+	}
+	item.QueryAllow, err = parseQueryAllow(request.QueryAllow)
 	if err != nil {
-		return nil, http.StatusInternalServerError, errors.Wrap(err, "Create handler error")
+		return model.BatchItem{}, http.StatusBadRequest, err
 	}
-	durationStorage := time.Since(startStorageAt)
 
-	u := url.URL{
-		Scheme: h.schema,
-		Host:   h.host,
-		Path:   c,
+	if request.Campaign != nil {
+		item.Campaign, err = h.parseCampaign(*request.Campaign)
+		if err != nil {
+			return model.BatchItem{}, http.StatusBadRequest, err
+		}
 	}
 
-	duration := time.Since(startAt)
-	action := "Generated link"
-	if tryFindExists {
-		action = "Try find or generated link"
+	deviceRules, status, err := h.parseDeviceRules(request.DeviceRules)
+	if err != nil {
+		return model.BatchItem{}, status, err
+	}
+	item.DeviceRules = deviceRules
+
+	var tryFindExists bool
+	if request.TryFindExists != nil {
+		tryFindExists = *request.TryFindExists
 	}
-	log.Infof("%v: %v, duration: %v, storage: %v", u.String(), action, duration, durationStorage)
 
-	return u.String(), http.StatusCreated, nil
+	return model.BatchItem{Item: item, TryFindExists: tryFindExists}, http.StatusOK, nil
 }
 
 func (h *handler) redirect(w http.ResponseWriter, r *http.Request) {
@@ -234,66 +450,171 @@ func (h *handler) redirect(w http.ResponseWriter, r *http.Request) {
 		metricStop(histogram)
 	}()
 
+	link, useCache, ok := h.resolveLink(w, r)
+	if !ok || !h.checkActive(w, r, link) {
+		return
+	}
+	if link.passwordHash != "" {
+		h.sendPasswordForm(w, "", http.StatusOK)
+		return
+	}
+	if !h.visit(w, r, link) {
+		return
+	}
+	status := h.redirectStatus(link)
+	setRedirectCacheControl(w, link, status)
+	h.sendRedirect(w, r, link, useCache, status)
+}
+
+// resolveLink returns link by code from url, error page is sent if link is not found
+func (h *handler) resolveLink(w http.ResponseWriter, r *http.Request) (cachedLink, bool, bool) {
 	code := chi.URLParam(r, "shortLink")
 
-	decodedId, err := base62.Decode(code)
-	if err != nil {
+	isAlias := h.alias.validate(code) == nil
+	if _, err := h.codec.Decode(code); err != nil && !isAlias {
 		h.sendHtmlError(
 			w,
 			`<h1 style="margin-top: 150px; text-align: center; font-size: 72px;">Can't decode code</h1>`,
 			http.StatusInternalServerError,
 		)
-		return
+		return cachedLink{}, false, false
 	}
 
-	uri, useCache, err := h.getUriById(decodedId)
+	link, useCache, err := h.getLinkByCode(code, isAlias)
 
 	if err != nil {
-		if errors.Is(err, model.ErrNoLink) {
-			log.Debugf("Short link not found or expired, id=%v", decodedId)
-		} else {
-			log.Warnf("Can't get url by id %v: %+v", decodedId, err)
+		var expired *model.ExpiredError
+		switch {
+		case errors.As(err, &expired):
+			log.Debugf("Short link is %v, code=%v", expired.Reason, code)
+			h.sendExpired(w, r, expired.Item.ExpiredURL)
+		case errors.Is(err, model.ErrNoLink):
+			log.Debugf("Short link not found, code=%v", code)
+			h.sendNotFound(w, r)
+		default:
+			log.Warnf("Can't get url by code %v: %+v", code, err)
+			h.sendNotFound(w, r)
 		}
-		if h.err404 != "" {
-			http.Redirect(w, r, h.err404, http.StatusMovedPermanently)
+		return cachedLink{}, false, false
+	}
+	return link, useCache, true
+}
+
+// visit counts visit of link with visit limit, expired page is sent if no visits are left
+func (h *handler) visit(w http.ResponseWriter, r *http.Request, link cachedLink) bool {
+	if link.maxVisits == 0 {
+		return true
+	}
+	if err := h.storage.Visit(link.id); err != nil {
+		if errors.Is(err, model.ErrNoLink) {
+			log.Debugf("Short link has no visits left, id=%v", link.id)
+			h.sendExpired(w, r, link.expiredUrl)
 		} else {
-			h.sendHtmlError(
-				w,
-				`<h1 style="margin-top: 150px; text-align: center; font-size: 72px;">Page not found</h1>`,
-				http.StatusNotFound,
-			)
+			log.Warnf("Can't visit link %v: %+v", link.id, err)
+			h.sendNotFound(w, r)
 		}
-		return
+		return false
 	}
+	return true
+}
 
-	query := r.URL.RawQuery
-	if query != "" {
-		glue := "?"
-		if strings.Contains(uri, "?") {
-			glue = "&"
-		}
-		uri = uri + glue + query
+func (h *handler) sendNotFound(w http.ResponseWriter, r *http.Request) {
+	if h.err404 != "" {
+		http.Redirect(w, r, h.err404, http.StatusMovedPermanently)
+		return
 	}
+	h.sendHtmlError(
+		w,
+		`<h1 style="margin-top: 150px; text-align: center; font-size: 72px;">Page not found</h1>`,
+		http.StatusNotFound,
+	)
+}
+
+// sendRedirect records click and redirects to url of device rule or link url with parameters of campaign
+// and query of request by query policy of link
+func (h *handler) sendRedirect(w http.ResponseWriter, r *http.Request, link cachedLink, useCache bool, status int) {
+	h.clicks.Record(model.Click{
+		LinkId:    link.id,
+		Time:      time.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		CacheHit:  useCache,
+	})
 
-	http.Redirect(w, r, uri, http.StatusMovedPermanently)
+	if len(link.deviceRules) > 0 {
+		// target depends on device, so caches must not share the redirect between devices
+		w.Header().Add("Vary", "User-Agent")
+	}
+	link.uri = h.applyCampaign(routeDevice(link, r.UserAgent()), link.campaign)
+	http.Redirect(w, r, h.forwardQuery(link, r.URL.RawQuery), status)
 }
 
-func (h *handler) getUriById(id uint64) (string, bool, error) {
-	cachedUri, err := h.cache.Get(id)
+func (h *handler) getLinkByCode(code string, isAlias bool) (cachedLink, bool, error) {
+	cached, err := h.cache.Get(code)
 	if err == nil {
-		return cachedUri.(string), true, nil
+		return cached.(cachedLink), true, nil
 	}
 	if !errors.Is(err, gcache.KeyNotFoundError) {
-		log.Errorf("Error on get long url from cache for %v: %v", id, err)
+		log.Errorf("Error on get long url from cache for %v: %v", code, err)
+	}
+	link, err := h.loadLink(code, isAlias)
+	if err != nil {
+		return cachedLink{}, false, errors.Wrap(err, "Can't get uri from storage")
+	}
+	// variants of code with trailing zero digits or other letter case are not cached,
+	// because cache is invalidated only by canonical code and alias
+	if code != h.codec.Encode(link.id) && code != link.alias {
+		return link, false, nil
 	}
-	uri, err := h.storage.Load(id)
+	if err := h.cacheLink(code, link); err != nil {
+		log.Errorf("Error on set long url to cache for %v: %v", code, err)
+	}
+	return link, false, err
+}
+
+// cacheLink keeps link in cache until its expiration, but not longer than cache ttl
+func (h *handler) cacheLink(code string, link cachedLink) error {
+	if !link.isCacheable() {
+		return nil
+	}
+	ttl := h.cacheTTL
+	if link.expires != nil {
+		untilExpires := time.Until(*link.expires)
+		if untilExpires <= 0 {
+			return nil
+		}
+		if ttl <= 0 || untilExpires < ttl {
+			ttl = untilExpires
+		}
+	}
+	if ttl <= 0 {
+		return h.cache.Set(code, link)
+	}
+	return h.cache.SetWithExpire(code, link, ttl)
+}
+
+// loadLink resolves code as alias at first, then as base62 encoded id
+func (h *handler) loadLink(code string, isAlias bool) (cachedLink, error) {
+	if isAlias {
+		item, err := h.storage.LoadByAlias(code)
+		if err == nil {
+			return newCachedLink(item), nil
+		}
+		// expired alias is not resolved as id, so its expiredUrl is used
+		var expired *model.ExpiredError
+		if !errors.Is(err, model.ErrNoLink) || errors.As(err, &expired) {
+			return cachedLink{}, err
+		}
+	}
+	id, err := h.codec.Decode(code)
 	if err != nil {
-		return "", false, errors.Wrap(err, "Can't get uri from storage")
+		return cachedLink{}, model.ErrNoLink
 	}
-	if err := h.cache.Set(id, uri); err != nil {
-		log.Errorf("Error on set long url to cache for %v: %v", id, err)
+	item, err := h.storage.Load(id)
+	if err != nil {
+		return cachedLink{}, err
 	}
-	return uri, false, err
+	return newCachedLink(item), nil
 }
 
 func (h *handler) sendHtmlError(w http.ResponseWriter, message string, code int) {
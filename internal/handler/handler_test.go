@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+const testToken = "test-token"
+
+// startTestServer starts handler with memory storage and returns its url
+func startTestServer(t *testing.T, conf config.Server) string {
+	return startTestApp(t, config.Config{Server: conf})
+}
+
+// startTestApp starts app with memory storage and test token by configuration and returns its url
+func startTestApp(t *testing.T, conf config.Config) string {
+	server := httptest.NewUnstartedServer(nil)
+	conf.Storage = config.Storage{Kind: "memory"}
+	conf.Server.Token = testToken
+	conf.Server.Schema = "http"
+	conf.Server.Prefix = server.Listener.Addr().String()
+	conf.Server.ReadTimeout = model.Duration{Duration: 10 * time.Second}
+	conf.Cache = config.Cache{Size: 100, TTL: model.Duration{Duration: time.Hour}}
+	app, err := NewApp(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Config.Handler = app.Handler
+	server.Start()
+	t.Cleanup(func() {
+		server.Close()
+		app.Close()
+	})
+	return server.URL
+}
+
+// apiRequest sends request with token and returns response with decoded body, body of response is closed
+func apiRequest(t *testing.T, method string, url string, token string, body string) (*http.Response, response) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Token", token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var ret response
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		t.Fatal(err)
+	}
+	return resp, ret
+}
+
+// createTestLink creates link by json body of create request and returns its code
+func createTestLink(t *testing.T, endpoint string, body string) string {
+	return createTestLinkBy(t, endpoint, testToken, body)
+}
+
+// createTestLinkBy creates link with token and returns its code
+func createTestLinkBy(t *testing.T, endpoint string, token string, body string) string {
+	resp, ret := apiRequest(t, http.MethodPost, endpoint+"/", token, body)
+	shortURL, ok := ret.Data.(string)
+	if resp.StatusCode != http.StatusCreated || !ok {
+		t.Fatalf("Can't create link %v: %v %v", body, resp.StatusCode, ret.Data)
+	}
+	return shortURL[strings.LastIndex(shortURL, "/")+1:]
+}
+
+// redirectLocation requests path with user agent and returns location of redirect
+func redirectLocation(t *testing.T, endpoint string, path string, userAgent string) string {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		t.Fatalf("Request %v isn't redirected: %v", path, resp.StatusCode)
+	}
+	return resp.Header.Get("Location")
+}
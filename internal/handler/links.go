@@ -0,0 +1,383 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/auth"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+type linkResponse struct {
+	Code     string     `json:"code"`
+	ShortURL string     `json:"shortUrl"`
+	URL      string     `json:"url"`
+	Alias    string     `json:"alias,omitempty"`
+	Expires  *time.Time `json:"expires"`
+	Created  time.Time  `json:"created"`
+	Owner    string     `json:"owner,omitempty"`
+	// ActiveFrom is time, when link starts to redirect
+	ActiveFrom *time.Time `json:"activeFrom,omitempty"`
+	ExpiredURL string     `json:"expiredUrl,omitempty"`
+	// Protected link asks password before redirect
+	Protected bool `json:"protected"`
+	// MaxVisits is visit limit and Visits is count of visits, they are omitted for unlimited link
+	MaxVisits int64 `json:"maxVisits,omitempty"`
+	Visits    int64 `json:"visits,omitempty"`
+	// RedirectType is status code of redirect, the default of server is returned, if link has no own type
+	RedirectType int `json:"redirectType"`
+	// QueryPolicy and QueryAllow are forwarding of query, the defaults of server are returned, if link has no own ones
+	QueryPolicy string   `json:"queryPolicy"`
+	QueryAllow  []string `json:"queryAllow,omitempty"`
+	Campaign    string   `json:"campaign,omitempty"`
+	// DeviceRules replace url for clients of their devices
+	DeviceRules []model.DeviceRule `json:"deviceRules,omitempty"`
+}
+
+type listResponse struct {
+	Items  []linkResponse `json:"items"`
+	Cursor string         `json:"cursor,omitempty"`
+}
+
+type updateRequest struct {
+	URL *string `json:"url"`
+	// ExpiredURL replaces url after expiration, empty string removes it
+	ExpiredURL *string `json:"expiredUrl"`
+	// RedirectType is status code of redirect, zero resets it to the default of server
+	RedirectType *int `json:"redirectType"`
+	// QueryPolicy and QueryAllow change forwarding of query, empty values reset them to the defaults of server
+	QueryPolicy *string   `json:"queryPolicy"`
+	QueryAllow  *[]string `json:"queryAllow"`
+	// Campaign changes campaign of link, empty string removes it
+	Campaign *string `json:"campaign"`
+	// DeviceRules replace rules of link, empty list removes them
+	DeviceRules *[]model.DeviceRule `json:"deviceRules"`
+	scheduleRequest
+}
+
+func (h *handler) linkRoutes(r chi.Router) {
+	r.Get("/", responseHandler(h.listLinks))
+	r.Get("/{code}", responseHandler(h.getLink))
+	r.Patch("/{code}", responseHandler(h.updateLink))
+	r.Delete("/{code}", responseHandler(h.deleteLink))
+	r.Get("/{code}/stats", responseHandler(h.linkStats))
+}
+
+// authorize returns token of request, if it grants scope
+func (h *handler) authorize(r *http.Request, scope string) (model.Token, error) {
+	token, ok := h.tokens.Authenticate(r.Header.Get("X-Token"))
+	if !ok || !auth.HasScope(token, scope) {
+		return model.Token{}, errors.New("Access denied")
+	}
+	return token, nil
+}
+
+func (h *handler) shortUrl(code string) string {
+	u := url.URL{
+		Scheme: h.schema,
+		Host:   h.host,
+		Path:   code,
+	}
+	return u.String()
+}
+
+func (h *handler) newLinkResponse(item model.Item) linkResponse {
+	code := item.Alias
+	if code == "" {
+		code = h.codec.Encode(item.Id)
+	}
+	queryPolicy, queryAllow := h.queryPolicyOf(item.QueryPolicy, item.QueryAllow)
+	return linkResponse{
+		Code:         code,
+		ShortURL:     h.shortUrl(code),
+		URL:          item.URL,
+		Alias:        item.Alias,
+		Expires:      item.Expires,
+		Created:      item.Created,
+		Owner:        item.Owner,
+		ActiveFrom:   item.ActiveFrom,
+		ExpiredURL:   item.ExpiredURL,
+		Protected:    item.IsProtected(),
+		MaxVisits:    item.MaxVisits,
+		Visits:       item.Visits,
+		RedirectType: h.redirectTypeOf(item.RedirectType),
+		QueryPolicy:  queryPolicy,
+		QueryAllow:   queryAllow,
+		Campaign:     item.Campaign,
+		DeviceRules:  item.DeviceRules,
+	}
+}
+
+// findLink returns item by code from url, which is alias or base62 encoded id,
+// links of other owners are not found unless token is admin
+func (h *handler) findLink(r *http.Request, token model.Token) (model.Item, int, error) {
+	code := chi.URLParam(r, "code")
+	var id uint64
+	if h.alias.validate(code) == nil {
+		aliasId, err := h.storage.FindAlias(code)
+		if err != nil {
+			return model.Item{}, http.StatusInternalServerError, errors.Wrap(err, "Can't find alias")
+		}
+		id = aliasId
+	}
+	if id == 0 {
+		decodedId, err := h.codec.Decode(code)
+		if err != nil {
+			return model.Item{}, http.StatusNotFound, model.ErrNoLink
+		}
+		id = decodedId
+	}
+	item, err := h.storage.Get(id)
+	if err != nil {
+		if errors.Is(err, model.ErrNoLink) {
+			return model.Item{}, http.StatusNotFound, model.ErrNoLink
+		}
+		return model.Item{}, http.StatusInternalServerError, errors.Wrap(err, "Can't get link")
+	}
+	if item.Owner != token.Name && !auth.HasScope(token, auth.ScopeAdmin) {
+		return model.Item{}, http.StatusNotFound, model.ErrNoLink
+	}
+	return item, http.StatusOK, nil
+}
+
+// invalidateCache removes item from redirect cache by all its codes
+func (h *handler) invalidateCache(item model.Item) {
+	h.cache.Remove(h.codec.Encode(item.Id))
+	if item.Alias != "" {
+		h.cache.Remove(item.Alias)
+	}
+}
+
+func (h *handler) getLink(r *http.Request) (interface{}, int, error) {
+	token, err := h.authorize(r, auth.ScopeRead)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	item, status, err := h.findLink(r, token)
+	if err != nil {
+		return nil, status, err
+	}
+	return h.newLinkResponse(item), http.StatusOK, nil
+}
+
+func (h *handler) updateLink(r *http.Request) (interface{}, int, error) {
+	token, err := h.authorize(r, auth.ScopeManage)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	var request updateRequest
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Can't read body of request")
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "Unable to info JSON request body")
+	}
+
+	item, status, err := h.findLink(r, token)
+	if err != nil {
+		return nil, status, err
+	}
+
+	if request.URL != nil {
+		uri, err := parseURL(*request.URL)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid url")
+		}
+		if err := h.urls.Check(uri); err != nil {
+			return nil, http.StatusUnprocessableEntity, err
+		}
+		item.URL = uri.String()
+	}
+	if err := request.scheduleRequest.apply(&item); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if request.ExpiredURL != nil {
+		expiredUrl, status, err := h.parseExpiredURL(*request.ExpiredURL)
+		if err != nil {
+			return nil, status, err
+		}
+		item.ExpiredURL = expiredUrl
+	}
+	if request.RedirectType != nil {
+		redirectType, err := parseRedirectType(*request.RedirectType)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		item.RedirectType = redirectType
+	}
+	if request.QueryPolicy != nil {
+		if item.QueryPolicy, err = parseQueryPolicy(*request.QueryPolicy); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+	if request.QueryAllow != nil {
+		if item.QueryAllow, err = parseQueryAllow(*request.QueryAllow); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+	if request.Campaign != nil {
+		if item.Campaign, err = h.parseCampaign(*request.Campaign); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+	if request.DeviceRules != nil {
+		deviceRules, status, err := h.parseDeviceRules(*request.DeviceRules)
+		if err != nil {
+			return nil, status, err
+		}
+		item.DeviceRules = deviceRules
+	}
+
+	if err := h.storage.Update(item); err != nil {
+		if errors.Is(err, model.ErrNoLink) {
+			return nil, http.StatusNotFound, model.ErrNoLink
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Update handler error")
+	}
+	h.invalidateCache(item)
+
+	response := h.newLinkResponse(item)
+	log.Infof("%v: Updated link", response.ShortURL)
+	return response, http.StatusOK, nil
+}
+
+func (h *handler) deleteLink(r *http.Request) (interface{}, int, error) {
+	token, err := h.authorize(r, auth.ScopeManage)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	item, status, err := h.findLink(r, token)
+	if err != nil {
+		return nil, status, err
+	}
+
+	if err := h.storage.Delete(item.Id); err != nil {
+		if errors.Is(err, model.ErrNoLink) {
+			return nil, http.StatusNotFound, model.ErrNoLink
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Delete handler error")
+	}
+	h.invalidateCache(item)
+
+	response := h.newLinkResponse(item)
+	log.Infof("%v: Deleted link", response.ShortURL)
+	return response, http.StatusOK, nil
+}
+
+// encodeCursor returns opaque cursor of last item of page
+func encodeCursor(item model.Item) string {
+	var created int64
+	if !item.Created.IsZero() {
+		created = item.Created.UnixNano()
+	}
+	raw := fmt.Sprintf("%d:%d", created, item.Id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*model.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	createdRaw, idRaw, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errors.New("invalid cursor format")
+	}
+	created, err := strconv.ParseInt(createdRaw, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(idRaw, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	ret := &model.Cursor{Id: id}
+	if created != 0 {
+		ret.Created = time.Unix(0, created)
+	}
+	return ret, nil
+}
+
+func (h *handler) listLinks(r *http.Request) (interface{}, int, error) {
+	token, err := h.authorize(r, auth.ScopeRead)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	query := model.ListQuery{Limit: defaultListLimit, Owner: token.Name}
+	params := r.URL.Query()
+	if auth.HasScope(token, auth.ScopeAdmin) {
+		query.Owner = params.Get("owner")
+	}
+	query.Campaign = params.Get("campaign")
+	if limit := params.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 || value > maxListLimit {
+			return nil, http.StatusBadRequest, errors.New(fmt.Sprintf("Limit must be from 1 to %v", maxListLimit))
+		}
+		query.Limit = value
+	}
+	switch params.Get("sort") {
+	case "", "created":
+	case "-created":
+		query.Desc = true
+	default:
+		return nil, http.StatusBadRequest, errors.New("Sort must be created or -created")
+	}
+	if cursor := params.Get("cursor"); cursor != "" {
+		value, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid cursor")
+		}
+		query.Cursor = value
+	}
+
+	items, err := h.storage.List(query)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "List handler error")
+	}
+
+	response := listResponse{Items: make([]linkResponse, 0, len(items))}
+	for _, item := range items {
+		response.Items = append(response.Items, h.newLinkResponse(item))
+	}
+	if len(items) == query.Limit {
+		response.Cursor = encodeCursor(items[len(items)-1])
+	}
+	return response, http.StatusOK, nil
+}
+
+func (h *handler) linkStats(r *http.Request) (interface{}, int, error) {
+	token, err := h.authorize(r, auth.ScopeRead)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	item, status, err := h.findLink(r, token)
+	if err != nil {
+		return nil, status, err
+	}
+
+	stat, err := h.storage.ClickStat(item.Id)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Stats handler error")
+	}
+	return stat, http.StatusOK, nil
+}
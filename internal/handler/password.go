@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/metrics"
+)
+
+// maxPasswordFormSize limits body of password form
+const maxPasswordFormSize = 4096
+
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Protected link</title></head>
+<body style="margin-top: 150px; text-align: center; font-family: sans-serif;">
+<h1>Link is protected by password</h1>
+{{if .}}<p style="color: red;">{{.}}</p>{{end}}
+<form method="post">
+<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// sendPasswordForm renders form, which posts password to the same url, so query of request is kept
+func (h *handler) sendPasswordForm(w http.ResponseWriter, message string, code int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := passwordForm.Execute(w, message); err != nil {
+		log.Errorf("Can't render password form: %+v", err)
+	}
+}
+
+// unlock redirects to protected link after correct password, attempts are throttled per link
+func (h *handler) unlock(w http.ResponseWriter, r *http.Request) {
+	link, _, ok := h.resolveLink(w, r)
+	if !ok || !h.checkActive(w, r, link) {
+		return
+	}
+	if link.passwordHash == "" {
+		if h.visit(w, r, link) {
+			h.sendRedirect(w, r, link, false, http.StatusSeeOther)
+		}
+		return
+	}
+
+	if allowed, retryAfter := h.passwordLimiter.Allow(strconv.FormatUint(link.id, 10)); !allowed {
+		metrics.RateLimitedPasswordCounter.Inc()
+		setRetryAfter(w, retryAfter)
+		h.sendPasswordForm(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasswordFormSize)
+	password := r.PostFormValue("password")
+	err := bcrypt.CompareHashAndPassword([]byte(link.passwordHash), []byte(password))
+	if err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			log.Errorf("Can't compare password of link %v: %+v", link.id, err)
+		}
+		h.sendPasswordForm(w, "Wrong password", http.StatusForbidden)
+		return
+	}
+
+	if !h.visit(w, r, link) {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	h.sendRedirect(w, r, link, false, http.StatusSeeOther)
+}
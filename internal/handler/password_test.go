@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+)
+
+// postPassword posts password form to link and returns response without following redirect
+func postPassword(t *testing.T, endpoint string, code string, password string) *http.Response {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	form := url.Values{"password": {password}}
+	resp, err := client.Post(endpoint+"/"+code, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	return resp
+}
+
+func TestUnlock(t *testing.T) {
+	endpoint := startTestServer(t, config.Server{})
+	code := createTestLink(t, endpoint, `{"url": "http://example.com/", "password": "secret"}`)
+
+	resp, err := http.Get(endpoint + "/" + code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "form is shown instead of redirect")
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+
+	tests := []struct {
+		name     string
+		password string
+		status   int
+		location string
+	}{
+		{name: "empty password", status: http.StatusForbidden},
+		{name: "wrong password", password: "wrong", status: http.StatusForbidden},
+		{name: "right password", password: "secret", status: http.StatusSeeOther, location: "http://example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postPassword(t, endpoint, code, tt.password)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, tt.location, resp.Header.Get("Location"))
+		})
+	}
+}
+
+func TestUnlock_NotReused(t *testing.T) {
+	endpoint := startTestServer(t, config.Server{})
+	body := `{"url": "http://example.com/", "tryFindExists": true}`
+	protected := createTestLink(t, endpoint, `{"url": "http://example.com/", "password": "secret"}`)
+	reusable := createTestLink(t, endpoint, body)
+	assert.NotEqual(t, protected, reusable, "protected link isn't shared")
+	assert.Equal(t, reusable, createTestLink(t, endpoint, body))
+}
+
+func TestUnlock_AttemptsAreLimited(t *testing.T) {
+	endpoint := startTestApp(t, config.Config{RateLimit: config.RateLimit{Password: config.Limit{Rate: 0.1, Burst: 2}}})
+	code := createTestLink(t, endpoint, `{"url": "http://example.com/", "password": "secret"}`)
+	other := createTestLink(t, endpoint, `{"url": "http://example.com/other", "password": "secret"}`)
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusForbidden, postPassword(t, endpoint, code, "wrong").StatusCode)
+	}
+	// even right password is rejected
+	assertRetryAfter(t, postPassword(t, endpoint, code, "secret"))
+
+	// attempts are limited per link
+	assert.Equal(t, http.StatusSeeOther, postPassword(t, endpoint, other, "secret").StatusCode)
+}
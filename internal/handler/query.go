@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// policies of forwarding query of request to url of link
+const (
+	// queryAppend appends query of request to query of url as is
+	queryAppend = "append"
+	// queryMergeOverride replaces parameters of url by parameters of request with the same name
+	queryMergeOverride = "merge-override"
+	// queryMergeKeep adds only parameters of request, which are absent in url
+	queryMergeKeep = "merge-keep"
+	// queryDrop doesn't forward query of request
+	queryDrop = "drop"
+)
+
+// parseURL parses absolute url like url.ParseRequestURI, but keeps fragment apart from path and query
+func parseURL(raw string) (*url.URL, error) {
+	withoutFragment, _, _ := strings.Cut(raw, "#")
+	if _, err := url.ParseRequestURI(withoutFragment); err != nil {
+		return nil, err
+	}
+	return url.Parse(raw)
+}
+
+func isQueryPolicy(policy string) bool {
+	switch policy {
+	case queryAppend, queryMergeOverride, queryMergeKeep, queryDrop:
+		return true
+	}
+	return false
+}
+
+// parseQueryPolicy validates query policy of request, empty policy is the default of server
+func parseQueryPolicy(policy string) (string, error) {
+	if policy != "" && !isQueryPolicy(policy) {
+		return "", errors.New("Query policy must be append, merge-override, merge-keep or drop")
+	}
+	return policy, nil
+}
+
+// parseQueryAllow validates names of forwarded parameters, empty list is the default of server
+func parseQueryAllow(names []string) ([]string, error) {
+	for _, name := range names {
+		if name == "" || strings.Contains(name, ",") {
+			return nil, errors.New("Query parameter name must not be empty or contain comma")
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	return names, nil
+}
+
+// queryPolicyOf returns query policy and allowed parameters of link, which are the defaults of server,
+// if link has no own ones
+func (h *handler) queryPolicyOf(policy string, allow []string) (string, []string) {
+	if policy == "" {
+		policy = h.queryPolicy
+	}
+	if len(allow) == 0 {
+		allow = h.queryAllow
+	}
+	return policy, allow
+}
+
+// forwardQuery returns url of link with query of request by query policy of link
+func (h *handler) forwardQuery(link cachedLink, rawQuery string) string {
+	policy, allow := h.queryPolicyOf(link.queryPolicy, link.queryAllow)
+	return forwardQuery(link.uri, rawQuery, policy, allow)
+}
+
+// forwardQuery adds allowed parameters of raw query to uri by policy, fragment of uri is kept at the end
+func forwardQuery(uri string, rawQuery string, policy string, allow []string) string {
+	if policy == queryDrop {
+		return uri
+	}
+	query := filterQuery(rawQuery, allow)
+	if query == "" {
+		return uri
+	}
+	target, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	switch policy {
+	case queryMergeOverride, queryMergeKeep:
+		incoming, _ := url.ParseQuery(query)
+		values := target.Query()
+		for name, value := range incoming {
+			if _, ok := values[name]; ok && policy == queryMergeKeep {
+				continue
+			}
+			values[name] = value
+		}
+		target.RawQuery = values.Encode()
+	default:
+		if target.RawQuery != "" {
+			target.RawQuery += "&" + query
+		} else {
+			target.RawQuery = query
+		}
+	}
+	return target.String()
+}
+
+// filterQuery returns parameters of raw query, which names are allowed, empty allow list permits all names
+func filterQuery(rawQuery string, allow []string) string {
+	if len(allow) == 0 || rawQuery == "" {
+		return rawQuery
+	}
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		name, _, _ := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(name)
+		if err != nil {
+			continue
+		}
+		if slices.Contains(allow, name) {
+			params = append(params, param)
+		}
+	}
+	return strings.Join(params, "&")
+}
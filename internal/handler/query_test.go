@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+)
+
+func TestRedirect_Query(t *testing.T) {
+	endpoint := startTestServer(t, config.Server{})
+
+	tests := []struct {
+		name   string
+		url    string
+		policy string
+		allow  []string
+		query  string
+		want   string
+	}{
+		{
+			name:  "append",
+			url:   "http://example.com/?a=1",
+			query: "b=2&b=3",
+			want:  "http://example.com/?a=1&b=2&b=3",
+		},
+		{
+			name:  "append duplicated parameter",
+			url:   "http://example.com/?a=1",
+			query: "a=2",
+			want:  "http://example.com/?a=1&a=2",
+		},
+		{
+			name:  "allow list",
+			url:   "http://example.com/?a=1",
+			allow: []string{"b"},
+			query: "b=2&c=3&b=4",
+			want:  "http://example.com/?a=1&b=2&b=4",
+		},
+		{
+			name:  "allow list of escaped name",
+			url:   "http://example.com/",
+			allow: []string{"utm source"},
+			query: "utm%20source=x&other=y",
+			want:  "http://example.com/?utm%20source=x",
+		},
+		{
+			name:  "allow list drops all parameters",
+			url:   "http://example.com/?a=1",
+			allow: []string{"b"},
+			query: "c=3",
+			want:  "http://example.com/?a=1",
+		},
+		{
+			name:   "merge override duplicated parameters",
+			url:    "http://example.com/?a=1&a=2&b=1",
+			policy: queryMergeOverride,
+			query:  "a=3&a=4",
+			want:   "http://example.com/?a=3&a=4&b=1",
+		},
+		{
+			name:   "merge keep duplicated parameters",
+			url:    "http://example.com/?a=1",
+			policy: queryMergeKeep,
+			query:  "a=2&c=3&c=4",
+			want:   "http://example.com/?a=1&c=3&c=4",
+		},
+		{
+			name:   "merge with allow list",
+			url:    "http://example.com/?a=1",
+			policy: queryMergeOverride,
+			allow:  []string{"a"},
+			query:  "a=2&b=3",
+			want:   "http://example.com/?a=2",
+		},
+		{
+			name:   "drop",
+			url:    "http://example.com/?a=1",
+			policy: queryDrop,
+			query:  "b=2",
+			want:   "http://example.com/?a=1",
+		},
+		{
+			name:  "append keeps fragment at the end",
+			url:   "http://example.com/path?a=1#top",
+			query: "b=2",
+			want:  "http://example.com/path?a=1&b=2#top",
+		},
+		{
+			name:   "merge keeps fragment at the end",
+			url:    "http://example.com/path#top",
+			policy: queryMergeOverride,
+			query:  "b=2",
+			want:   "http://example.com/path?b=2#top",
+		},
+		{
+			name:  "empty query",
+			url:   "http://example.com/path?a=1#top",
+			query: "",
+			want:  "http://example.com/path?a=1#top",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := createRequest{URL: tt.url, QueryAllow: tt.allow}
+			if tt.policy != "" {
+				request.QueryPolicy = &tt.policy
+			}
+			body, err := json.Marshal(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code := createTestLink(t, endpoint, string(body))
+
+			path := "/" + code
+			if tt.query != "" {
+				path += "?" + tt.query
+			}
+			assert.Equal(t, tt.want, redirectLocation(t, endpoint, path, ""))
+		})
+	}
+}
+
+func TestRedirect_QueryServerDefaults(t *testing.T) {
+	endpoint := startTestServer(t, config.Server{
+		Query: config.Query{Policy: queryMergeKeep, Allow: []string{"a", "b"}},
+	})
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "server policy and allow list",
+			body: `{"url": "http://example.com/?a=1"}`,
+			want: "http://example.com/?a=1&b=3",
+		},
+		{
+			name: "link policy",
+			body: `{"url": "http://example.com/?a=1", "queryPolicy": "append"}`,
+			want: "http://example.com/?a=1&a=2&b=3",
+		},
+		{
+			name: "link allow list",
+			body: `{"url": "http://example.com/?a=1", "queryAllow": ["c"]}`,
+			want: "http://example.com/?a=1&c=4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := createTestLink(t, endpoint, tt.body)
+			assert.Equal(t, tt.want, redirectLocation(t, endpoint, "/"+code+"?a=2&b=3&c=4", ""))
+		})
+	}
+}
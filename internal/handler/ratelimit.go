@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/ratelimit"
+)
+
+// rateLimit rejects requests with 429 by reject, when limiter has no tokens for key of request
+func rateLimit(
+	limiter *ratelimit.Limiter,
+	key func(r *http.Request) string,
+	counter prometheus.Counter,
+	reject http.HandlerFunc,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, retryAfter := limiter.Allow(key(r))
+			if !allowed {
+				counter.Inc()
+				setRetryAfter(w, retryAfter)
+				reject(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// batchRateLimit is rateLimit, which takes a token per item of batch in body of request,
+// batch larger than burst of limiter is rejected with 400, because it is never allowed
+func batchRateLimit(
+	limiter *ratelimit.Limiter,
+	key func(r *http.Request) string,
+	counter prometheus.Counter,
+	reject http.HandlerFunc,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// body is read before authorization, so its size is limited
+			body, status, err := readBatchBody(w, r)
+			if err != nil {
+				writeApiError(w, status, err.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// invalid batch takes a single token, it is rejected by handler
+			n := 1
+			var items []json.RawMessage
+			if err := json.Unmarshal(body, &items); err == nil && len(items) > n {
+				n = len(items)
+			}
+			if burst := limiter.Burst(); burst > 0 && n > burst {
+				writeApiError(w, http.StatusBadRequest, fmt.Sprintf("Batch size must not exceed rate limit burst %v", burst))
+				return
+			}
+			allowed, retryAfter := limiter.AllowN(key(r), n)
+			if !allowed {
+				counter.Inc()
+				setRetryAfter(w, retryAfter)
+				reject(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+func rejectApi(w http.ResponseWriter, r *http.Request) {
+	writeApiError(w, http.StatusTooManyRequests, "Too many requests")
+}
+
+func writeApiError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response{Data: message, Success: false})
+}
+
+func (h *handler) rejectRedirect(w http.ResponseWriter, r *http.Request) {
+	h.sendHtmlError(
+		w,
+		`<h1 style="margin-top: 150px; text-align: center; font-size: 72px;">Too many requests</h1>`,
+		http.StatusTooManyRequests,
+	)
+}
+
+// clientIp returns ip of request, middleware.RealIP has already replaced remote address by forwarded one
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// tokenOrIp returns name of api token, requests with invalid token are limited by ip
+func (h *handler) tokenOrIp(r *http.Request) string {
+	if token, ok := h.tokens.Authenticate(r.Header.Get("X-Token")); ok {
+		return "token:" + token.Name
+	}
+	return "ip:" + clientIp(r)
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+)
+
+// assertRetryAfter checks that response is rejected by limiter with rate 0.1, so the next token comes
+// in 10s at most, bucket is refilled during slow requests
+func assertRetryAfter(t *testing.T, resp *http.Response) {
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.True(t, retryAfter >= 1 && retryAfter <= 10, "Retry-After %v is from 1 to 10", retryAfter)
+}
+
+func TestRateLimit_Create(t *testing.T) {
+	endpoint := startTestApp(t, config.Config{RateLimit: config.RateLimit{Create: config.Limit{Rate: 0.1, Burst: 3}}})
+	const body = `{"url": "http://example.com/"}`
+
+	for i := 0; i < 3; i++ {
+		createTestLink(t, endpoint, body)
+	}
+	resp, _ := apiRequest(t, http.MethodPost, endpoint+"/", testToken, body)
+	assertRetryAfter(t, resp)
+
+	// invalid tokens are limited by ip and don't spend tokens of api token
+	resp, _ = apiRequest(t, http.MethodPost, endpoint+"/", "invalid", body)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestRateLimit_Batch(t *testing.T) {
+	endpoint := startTestApp(t, config.Config{RateLimit: config.RateLimit{Create: config.Limit{Rate: 0.1, Burst: 3}}})
+	batch := endpoint + "/api/v1/links:batch"
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "larger than burst", body: `[{"url": "http://a.com/"}, {"url": "http://b.com/"}, {"url": "http://c.com/"}, {"url": "http://d.com/"}]`, status: http.StatusBadRequest},
+		{name: "takes a token per item", body: `[{"url": "http://a.com/"}, {"url": "http://b.com/"}]`, status: http.StatusOK},
+		{name: "more than left", body: `[{"url": "http://a.com/"}, {"url": "http://b.com/"}]`, status: http.StatusTooManyRequests},
+		{name: "rest", body: `[{"url": "http://a.com/"}]`, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _ := apiRequest(t, http.MethodPost, batch, testToken, tt.body)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+	resp, _ := apiRequest(t, http.MethodPost, endpoint+"/", testToken, `{"url": "http://example.com/"}`)
+	assertRetryAfter(t, resp)
+}
+
+func TestRateLimit_Redirect(t *testing.T) {
+	endpoint := startTestApp(t, config.Config{RateLimit: config.RateLimit{Redirect: config.Limit{Rate: 0.1, Burst: 2}}})
+	code := createTestLink(t, endpoint, `{"url": "http://example.com/"}`)
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, "http://example.com/", redirectLocation(t, endpoint, "/"+code, ""))
+	}
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(endpoint + "/" + code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	assertRetryAfter(t, resp)
+}
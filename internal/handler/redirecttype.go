@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// permanentMaxAge is how long clients may cache permanent redirect of link without expiration
+const permanentMaxAge = 365 * 24 * time.Hour
+
+func isRedirectType(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+func isPermanentRedirect(status int) bool {
+	return status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect
+}
+
+// parseRedirectType validates redirect type of request, zero is the default of server
+func parseRedirectType(value int) (int, error) {
+	if value != 0 && !isRedirectType(value) {
+		return 0, errors.New("Redirect type must be 301, 302, 307 or 308")
+	}
+	return value, nil
+}
+
+// redirectTypeOf returns redirect status of link, which is the default of server, if link has no own type
+func (h *handler) redirectTypeOf(linkType int) int {
+	if linkType == 0 {
+		return h.redirectType
+	}
+	return linkType
+}
+
+// redirectStatus returns redirect status of link, links with visit limit are never redirected permanently
+func (h *handler) redirectStatus(link cachedLink) int {
+	status := h.redirectTypeOf(link.redirectType)
+	if link.maxVisits > 0 {
+		// browser must not repeat limited redirect from its cache
+		switch status {
+		case http.StatusMovedPermanently:
+			status = http.StatusFound
+		case http.StatusPermanentRedirect:
+			status = http.StatusTemporaryRedirect
+		}
+	}
+	return status
+}
+
+// setRedirectCacheControl lets clients cache permanent redirect until expiration of link,
+// temporary redirect is not cached, so every visit reaches the server
+func setRedirectCacheControl(w http.ResponseWriter, link cachedLink, status int) {
+	if !isPermanentRedirect(status) {
+		w.Header().Set("Cache-Control", "no-store")
+		return
+	}
+	maxAge := permanentMaxAge
+	if link.expires != nil {
+		maxAge = min(maxAge, max(time.Until(*link.expires), 0))
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+}
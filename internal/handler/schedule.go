@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+// scheduleRequest is activation window of link, nil field keeps current value, empty string removes it
+type scheduleRequest struct {
+	// Expires in RFC3339
+	Expires *string `json:"expires"`
+	// ExpiresIn is expiration relative to now, e.g. 24h
+	ExpiresIn *model.Duration `json:"expiresIn"`
+	// ActiveFrom in RFC3339
+	ActiveFrom *string `json:"activeFrom"`
+}
+
+// apply sets activation window of item
+func (s scheduleRequest) apply(item *model.Item) error {
+	if s.Expires != nil && s.ExpiresIn != nil {
+		return errors.New("Only one of expires and expiresIn may be set")
+	}
+	if s.Expires != nil {
+		expires, err := parseOptionalTime(*s.Expires)
+		if err != nil {
+			return errors.New("Invalid expiration date")
+		}
+		item.Expires = expires
+	}
+	if s.ExpiresIn != nil {
+		if s.ExpiresIn.Duration <= 0 {
+			return errors.New("Expires in must be positive")
+		}
+		expires := time.Now().Add(s.ExpiresIn.Duration).Truncate(time.Second)
+		item.Expires = &expires
+	}
+	if s.ActiveFrom != nil {
+		activeFrom, err := parseOptionalTime(*s.ActiveFrom)
+		if err != nil {
+			return errors.New("Invalid activation date")
+		}
+		item.ActiveFrom = activeFrom
+	}
+	if item.ActiveFrom != nil && item.Expires != nil && !item.ActiveFrom.Before(*item.Expires) {
+		return errors.New("Activation date must be before expiration date")
+	}
+	return nil
+}
+
+// parseOptionalTime parses RFC3339 time, empty value is no time
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	ret, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// checkActive sends not active page or redirects to server.notActive url, if link is not activated yet
+func (h *handler) checkActive(w http.ResponseWriter, r *http.Request, link cachedLink) bool {
+	if link.activeFrom == nil || !time.Now().Before(*link.activeFrom) {
+		return true
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if h.notActive != "" {
+		http.Redirect(w, r, h.notActive, http.StatusFound)
+		return false
+	}
+	h.sendHtmlError(
+		w,
+		fmt.Sprintf(
+			`<h1 style="margin-top: 150px; text-align: center; font-size: 72px;">Link is not available yet</h1>`+
+				`<p style="text-align: center;">It will be available from %v</p>`,
+			html.EscapeString(link.activeFrom.UTC().Format(time.RFC1123)),
+		),
+		http.StatusNotFound,
+	)
+	return false
+}
+
+// parseExpiredURL validates url, which replaces link after expiration, empty value is no url
+func (h *handler) parseExpiredURL(value string) (string, int, error) {
+	if value == "" {
+		return "", http.StatusOK, nil
+	}
+	uri, err := parseURL(value)
+	if err != nil {
+		return "", http.StatusBadRequest, errors.New("Invalid expired url")
+	}
+	if err := h.urls.Check(uri); err != nil {
+		return "", http.StatusUnprocessableEntity, err
+	}
+	return uri.String(), http.StatusOK, nil
+}
+
+// sendExpired redirects to expiredUrl of link, if it is set, otherwise expired link is shown like absent one,
+// but built-in page tells that it is expired
+func (h *handler) sendExpired(w http.ResponseWriter, r *http.Request, expiredUrl string) {
+	if expiredUrl != "" {
+		http.Redirect(w, r, expiredUrl, http.StatusFound)
+		return
+	}
+	if h.err404 != "" {
+		http.Redirect(w, r, h.err404, http.StatusMovedPermanently)
+		return
+	}
+	h.sendHtmlError(
+		w,
+		`<h1 style="margin-top: 150px; text-align: center; font-size: 72px;">Link has expired</h1>`,
+		http.StatusGone,
+	)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/auth"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+type tokenResponse struct {
+	Name    string    `json:"name"`
+	Scopes  []string  `json:"scopes"`
+	Created time.Time `json:"created"`
+	// Token is secret, it is returned only on issue
+	Token string `json:"token,omitempty"`
+}
+
+type issueTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func (h *handler) tokenRoutes(r chi.Router) {
+	r.Get("/", responseHandler(h.listTokens))
+	r.Post("/", responseHandler(h.issueToken))
+	r.Delete("/{name}", responseHandler(h.revokeToken))
+}
+
+func newTokenResponse(token model.Token) tokenResponse {
+	return tokenResponse{
+		Name:    token.Name,
+		Scopes:  token.Scopes,
+		Created: token.Created,
+	}
+}
+
+func (h *handler) listTokens(r *http.Request) (interface{}, int, error) {
+	if _, err := h.authorize(r, auth.ScopeAdmin); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	tokens, err := h.tokens.List()
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "List tokens handler error")
+	}
+	response := make([]tokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		response = append(response, newTokenResponse(token))
+	}
+	return response, http.StatusOK, nil
+}
+
+func (h *handler) issueToken(r *http.Request) (interface{}, int, error) {
+	admin, err := h.authorize(r, auth.ScopeAdmin)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	var request issueTokenRequest
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Can't read body of request")
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(err, "Unable to info JSON request body")
+	}
+	if err := auth.Validate(request.Name, request.Scopes); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	token, secret, err := h.tokens.Issue(request.Name, request.Scopes)
+	if err != nil {
+		if errors.Is(err, model.ErrItemDuplicated) {
+			return nil, http.StatusConflict, err
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Issue token handler error")
+	}
+
+	log.Infof("Token %v with scopes %v issued by %v", token.Name, token.Scopes, admin.Name)
+	response := newTokenResponse(token)
+	response.Token = secret
+	return response, http.StatusCreated, nil
+}
+
+func (h *handler) revokeToken(r *http.Request) (interface{}, int, error) {
+	admin, err := h.authorize(r, auth.ScopeAdmin)
+	if err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	name := chi.URLParam(r, "name")
+	if err := h.tokens.Revoke(name); err != nil {
+		if errors.Is(err, model.ErrNoToken) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(err, "Revoke token handler error")
+	}
+
+	log.Infof("Token %v revoked by %v", name, admin.Name)
+	return name, http.StatusOK, nil
+}
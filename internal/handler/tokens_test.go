@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+)
+
+// issueTestToken issues token by admin and returns its secret
+func issueTestToken(t *testing.T, endpoint string, body string) string {
+	resp, ret := apiRequest(t, http.MethodPost, endpoint+"/api/v1/tokens", testToken, body)
+	data, ok := ret.Data.(map[string]interface{})
+	if resp.StatusCode != http.StatusCreated || !ok {
+		t.Fatalf("Can't issue token %v: %v %v", body, resp.StatusCode, ret.Data)
+	}
+	return data["token"].(string)
+}
+
+func TestTokens_Issue(t *testing.T) {
+	endpoint := startTestServer(t, config.Server{})
+	creator := issueTestToken(t, endpoint, `{"name": "creator", "scopes": ["create"]}`)
+
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		status int
+	}{
+		{name: "not admin", token: creator, body: `{"name": "bot", "scopes": ["read"]}`, status: http.StatusForbidden},
+		{name: "duplicated name", token: testToken, body: `{"name": "creator", "scopes": ["read"]}`, status: http.StatusConflict},
+		{name: "unknown scope", token: testToken, body: `{"name": "bot", "scopes": ["delete"]}`, status: http.StatusBadRequest},
+		{name: "reserved name", token: testToken, body: `{"name": "config", "scopes": ["read"]}`, status: http.StatusBadRequest},
+		{name: "valid", token: testToken, body: `{"name": "bot", "scopes": ["read"]}`, status: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _ := apiRequest(t, http.MethodPost, endpoint+"/api/v1/tokens", tt.token, tt.body)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestTokens_Revoke(t *testing.T) {
+	endpoint := startTestServer(t, config.Server{})
+	secret := issueTestToken(t, endpoint, `{"name": "bot", "scopes": ["create"]}`)
+	createTestLinkBy(t, endpoint, secret, `{"url": "http://example.com/"}`)
+
+	resp, _ := apiRequest(t, http.MethodDelete, endpoint+"/api/v1/tokens/bot", testToken, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = apiRequest(t, http.MethodDelete, endpoint+"/api/v1/tokens/bot", testToken, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = apiRequest(t, http.MethodPost, endpoint+"/", secret, `{"url": "http://example.com/"}`)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "revoked token is rejected")
+}
+
+func TestLinks_ScopesAndOwnership(t *testing.T) {
+	endpoint := startTestServer(t, config.Server{})
+	owner := issueTestToken(t, endpoint, `{"name": "owner", "scopes": ["create", "read", "manage"]}`)
+	other := issueTestToken(t, endpoint, `{"name": "other", "scopes": ["create", "read", "manage"]}`)
+	reader := issueTestToken(t, endpoint, `{"name": "reader", "scopes": ["read"]}`)
+	code := createTestLinkBy(t, endpoint, owner, `{"url": "http://example.com/"}`)
+	link := endpoint + "/api/v1/links/" + code
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		body   string
+		status int
+	}{
+		{name: "create without scope", method: http.MethodPost, token: reader, body: `{"url": "http://example.com/"}`, status: http.StatusForbidden},
+		{name: "read by owner", method: http.MethodGet, token: owner, status: http.StatusOK},
+		{name: "read by admin", method: http.MethodGet, token: testToken, status: http.StatusOK},
+		// links of other owners are hidden
+		{name: "read by other", method: http.MethodGet, token: other, status: http.StatusNotFound},
+		{name: "read by reader of other owner", method: http.MethodGet, token: reader, status: http.StatusNotFound},
+		{name: "read without token", method: http.MethodGet, status: http.StatusForbidden},
+		{name: "update without scope", method: http.MethodPatch, token: reader, body: `{"url": "http://example.com/new"}`, status: http.StatusForbidden},
+		{name: "update by other", method: http.MethodPatch, token: other, body: `{"url": "http://example.com/new"}`, status: http.StatusNotFound},
+		{name: "delete by other", method: http.MethodDelete, token: other, status: http.StatusNotFound},
+		{name: "delete by owner", method: http.MethodDelete, token: owner, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := link
+			if tt.method == http.MethodPost {
+				url = endpoint + "/"
+			}
+			resp, _ := apiRequest(t, tt.method, url, tt.token, tt.body)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
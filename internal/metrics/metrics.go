@@ -31,6 +31,33 @@ var (
 		ConstLabels: map[string]string{"cache": "false"},
 		Buckets:     buckets,
 	})
+	ClicksSavedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: prefix,
+		Name:      "clicks_saved",
+	})
+	ClicksDroppedCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: prefix,
+		Name:      "clicks_dropped",
+	})
+	IdCollisionCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: prefix,
+		Name:      "id_collisions",
+	})
+	RateLimitedCreateCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace:   prefix,
+		Name:        "rate_limited",
+		ConstLabels: map[string]string{"limit": "create"},
+	})
+	RateLimitedRedirectCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace:   prefix,
+		Name:        "rate_limited",
+		ConstLabels: map[string]string{"limit": "redirect"},
+	})
+	RateLimitedPasswordCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace:   prefix,
+		Name:        "rate_limited",
+		ConstLabels: map[string]string{"limit": "password"},
+	})
 )
 
 func init() {
@@ -40,6 +67,12 @@ func init() {
 		GenerateHistogram,
 		ViewCacheHistogram,
 		ViewNoCacheHistogram,
+		ClicksSavedCounter,
+		ClicksDroppedCounter,
+		IdCollisionCounter,
+		RateLimitedCreateCounter,
+		RateLimitedRedirectCounter,
+		RateLimitedPasswordCounter,
 	)
 }
 
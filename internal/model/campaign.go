@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// Campaign is named template of query parameters, e.g. utm_source, which are added to url of its links on redirect
+type Campaign struct {
+	Name    string            `json:"name"`
+	Params  map[string]string `json:"params"`
+	Created time.Time         `json:"created"`
+}
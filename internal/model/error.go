@@ -1,7 +1,37 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrNoLink = errors.New("no data")
 
 var ErrItemDuplicated = errors.New("item duplicated")
+
+var ErrAliasDuplicated = fmt.Errorf("alias is taken: %w", ErrItemDuplicated)
+
+var ErrNoToken = errors.New("no token")
+
+var ErrTokenDuplicated = fmt.Errorf("token name is taken: %w", ErrItemDuplicated)
+
+var ErrNoCampaign = errors.New("no campaign")
+
+const (
+	ReasonExpired   = "expired"
+	ReasonExhausted = "exhausted"
+)
+
+// ExpiredError is returned for item, which was expired or has no visits left, but is not cleaned yet
+type ExpiredError struct {
+	Item   Item
+	Reason string
+}
+
+func (e *ExpiredError) Error() string {
+	return "link is " + e.Reason
+}
+
+func (e *ExpiredError) Unwrap() error {
+	return ErrNoLink
+}
@@ -3,13 +3,142 @@ package model
 import (
 	"encoding/json"
 	"errors"
+	"sort"
 	"time"
 )
 
 type Item struct {
 	Id      uint64     `json:"id" redis:"id"`
 	URL     string     `json:"url" redis:"url"`
+	Alias   string     `json:"alias,omitempty" redis:"alias"`
 	Expires *time.Time `json:"expires" redis:"expires"`
+	Created time.Time  `json:"created" redis:"created"`
+	// ActiveFrom is time of activation, item doesn't redirect before it
+	ActiveFrom *time.Time `json:"activeFrom,omitempty" redis:"active_from"`
+	// ExpiredURL replaces URL after expiration until item is cleaned
+	ExpiredURL string `json:"expiredUrl,omitempty" redis:"expired_url"`
+	// RedirectType is status code of redirect, zero is the default of server
+	RedirectType int `json:"redirectType,omitempty" redis:"redirect_type"`
+	// QueryPolicy is policy of forwarding query of request to URL, empty is the default of server
+	QueryPolicy string `json:"queryPolicy,omitempty" redis:"query_policy"`
+	// QueryAllow is names of forwarded query parameters, empty is the default of server
+	QueryAllow []string `json:"queryAllow,omitempty"`
+	// Campaign is name of campaign, which parameters are added to URL on redirect
+	Campaign string `json:"campaign,omitempty" redis:"campaign"`
+	// DeviceRules replace URL for clients of their devices
+	DeviceRules []DeviceRule `json:"deviceRules,omitempty"`
+	// Owner is name of token, which created item
+	Owner string `json:"owner,omitempty" redis:"owner"`
+	// PasswordHash is bcrypt hash of password, which is asked before redirect
+	PasswordHash string `json:"passwordHash,omitempty" redis:"password_hash"`
+	// MaxVisits limits count of redirects, zero is no limit
+	MaxVisits int64 `json:"maxVisits,omitempty" redis:"max_visits"`
+	// Visits is count of redirects, it is counted only for links with visit limit
+	Visits int64 `json:"visits,omitempty" redis:"visits"`
+}
+
+// CheckActive returns *ExpiredError if item is expired at the time or has no visits left
+func (i Item) CheckActive(now time.Time) error {
+	if i.Expires != nil && i.Expires.Before(now) {
+		return &ExpiredError{Item: i, Reason: ReasonExpired}
+	}
+	if i.IsExhausted() {
+		return &ExpiredError{Item: i, Reason: ReasonExhausted}
+	}
+	return nil
+}
+
+// IsExhausted reports whether item has no visits left, such item behaves like absent one
+func (i Item) IsExhausted() bool {
+	return i.MaxVisits > 0 && i.Visits >= i.MaxVisits
+}
+
+// IsReusable reports whether item may be returned to another creator of the same url
+func (i Item) IsReusable() bool {
+	return !i.IsProtected() && i.MaxVisits == 0
+}
+
+// IsProtected reports whether item redirects only after password
+func (i Item) IsProtected() bool {
+	return i.PasswordHash != ""
+}
+
+// DeviceRule redirects clients of device to its own URL
+type DeviceRule struct {
+	Device string `json:"device"`
+	URL    string `json:"url"`
+}
+
+// EncodeDeviceRules returns json of rules for text fields of storages, no rules is empty string
+func EncodeDeviceRules(rules []DeviceRule) string {
+	if len(rules) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(rules)
+	return string(b)
+}
+
+// DecodeDeviceRules parses json of rules, empty string is no rules
+func DecodeDeviceRules(raw string) ([]DeviceRule, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var rules []DeviceRule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// BatchItem is item of batch creation, TryFindExists returns existing link with the same url instead
+type BatchItem struct {
+	Item          Item
+	TryFindExists bool
+}
+
+// BatchResult is short code of saved item of batch or error, which prevented its creation
+type BatchResult struct {
+	Code string
+	Err  error
+}
+
+// Cursor points to the last item of the previous page of list
+type Cursor struct {
+	Created time.Time
+	Id      uint64
+}
+
+// ListQuery selects page of items ordered by creation time and id
+type ListQuery struct {
+	Cursor *Cursor
+	Limit  int
+	Desc   bool
+	// Owner filters items by owner if it is not empty
+	Owner string
+	// Campaign filters items by campaign if it is not empty
+	Campaign string
+}
+
+// Matches reports whether item passes query filters
+func (q ListQuery) Matches(item Item) bool {
+	return (q.Owner == "" || item.Owner == q.Owner) && (q.Campaign == "" || item.Campaign == q.Campaign)
+}
+
+// After reports whether item is placed after the cursor in query order
+func (q ListQuery) After(item Item) bool {
+	if q.Cursor == nil {
+		return true
+	}
+	if item.Created.Equal(q.Cursor.Created) {
+		if q.Desc {
+			return item.Id < q.Cursor.Id
+		}
+		return item.Id > q.Cursor.Id
+	}
+	if q.Desc {
+		return item.Created.Before(q.Cursor.Created)
+	}
+	return item.Created.After(q.Cursor.Created)
 }
 
 type Duration struct {
@@ -40,3 +169,68 @@ func (d *Duration) UnmarshalJSON(b []byte) error {
 		return errors.New("invalid duration")
 	}
 }
+
+// Click is a single redirect of link
+type Click struct {
+	LinkId    uint64    `json:"linkId"`
+	Time      time.Time `json:"time"`
+	Referrer  string    `json:"referrer"`
+	UserAgent string    `json:"userAgent"`
+	Country   string    `json:"country"`
+	CacheHit  bool      `json:"cacheHit"`
+}
+
+// ClickStat is aggregated clicks of link
+type ClickStat struct {
+	Total     int64         `json:"total"`
+	CacheHits int64         `json:"cacheHits"`
+	Daily     []DailyClicks `json:"daily"`
+}
+
+type DailyClicks struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// Add sums clicks of other stat into stat
+func (s *ClickStat) Add(other ClickStat) {
+	s.Total += other.Total
+	s.CacheHits += other.CacheHits
+	daily := make(map[string]int64, len(s.Daily))
+	for _, day := range s.Daily {
+		daily[day.Date] = day.Count
+	}
+	for _, day := range other.Daily {
+		daily[day.Date] += day.Count
+	}
+	s.Daily = make([]DailyClicks, 0, len(daily))
+	for date, count := range daily {
+		s.Daily = append(s.Daily, DailyClicks{Date: date, Count: count})
+	}
+	sort.Slice(s.Daily, func(i, j int) bool {
+		return s.Daily[i].Date < s.Daily[j].Date
+	})
+}
+
+// ClickDateLayout is layout of DailyClicks date, days are in UTC
+const ClickDateLayout = "2006-01-02"
+
+// AggregateClicks counts clicks in total and by days
+func AggregateClicks(clicks []Click) ClickStat {
+	stat := ClickStat{Daily: []DailyClicks{}}
+	daily := make(map[string]int64)
+	for _, click := range clicks {
+		stat.Total++
+		if click.CacheHit {
+			stat.CacheHits++
+		}
+		daily[click.Time.UTC().Format(ClickDateLayout)]++
+	}
+	for date, count := range daily {
+		stat.Daily = append(stat.Daily, DailyClicks{Date: date, Count: count})
+	}
+	sort.Slice(stat.Daily, func(i, j int) bool {
+		return stat.Daily[i].Date < stat.Daily[j].Date
+	})
+	return stat
+}
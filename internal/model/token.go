@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Token is named api token, only hash of its secret is stored
+type Token struct {
+	Name    string    `json:"name"`
+	Hash    string    `json:"hash"`
+	Scopes  []string  `json:"scopes"`
+	Created time.Time `json:"created"`
+}
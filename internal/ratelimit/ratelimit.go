@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+)
+
+const cleanupInterval = time.Minute
+
+// Limiter keeps token bucket per key, buckets of idle keys are dropped
+type Limiter struct {
+	limit       rate.Limit
+	burst       int
+	idleTimeout time.Duration
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	cleanedAt   time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// New returns limiter, nil if rate is not positive, that means no limit
+func New(conf config.Limit) *Limiter {
+	if conf.Rate <= 0 {
+		return nil
+	}
+	burst := conf.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(conf.Rate))
+	}
+	// bucket, which is idle longer than its refill time, is full and may be dropped
+	idleTimeout := time.Duration(float64(burst) / conf.Rate * float64(time.Second))
+	if idleTimeout < cleanupInterval {
+		idleTimeout = cleanupInterval
+	}
+	return &Limiter{
+		limit:       rate.Limit(conf.Rate),
+		burst:       burst,
+		idleTimeout: idleTimeout,
+		buckets:     make(map[string]*bucket),
+		cleanedAt:   time.Now(),
+	}
+}
+
+// Allow takes token from bucket of key, otherwise returns time to wait for the next token,
+// nil limiter allows everything
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	return l.AllowN(key, 1)
+}
+
+// AllowN takes n tokens at once from bucket of key, n greater than burst is never allowed
+func (l *Limiter) AllowN(key string, n int) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	if now.Sub(l.cleanedAt) > cleanupInterval {
+		l.cleanup(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.seen = now
+	l.mu.Unlock()
+
+	reservation := b.limiter.ReserveN(now, n)
+	if !reservation.OK() {
+		return false, 0
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// Burst returns max number of tokens taken at once, zero for nil limiter
+func (l *Limiter) Burst() int {
+	if l == nil {
+		return 0
+	}
+	return l.burst
+}
+
+func (l *Limiter) cleanup(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.seen) > l.idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+	l.cleanedAt = now
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  config.Limit
+		burst int
+	}{
+		{name: "disabled"},
+		{name: "negative rate", conf: config.Limit{Rate: -1}},
+		{name: "burst by rate", conf: config.Limit{Rate: 2.5}, burst: 3},
+		{name: "configured burst", conf: config.Limit{Rate: 1, Burst: 10}, burst: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.conf)
+			assert.Equal(t, tt.burst == 0, l == nil)
+			assert.Equal(t, tt.burst, l.Burst())
+		})
+	}
+}
+
+func TestLimiter_NilAllowsEverything(t *testing.T) {
+	var l *Limiter
+	for i := 0; i < 100; i++ {
+		ok, wait := l.AllowN("key", 1000)
+		assert.True(t, ok)
+		assert.Zero(t, wait)
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l := New(config.Limit{Rate: 1, Burst: 2})
+	for i := 0; i < 2; i++ {
+		ok, wait := l.Allow("a")
+		assert.True(t, ok)
+		assert.Zero(t, wait)
+	}
+
+	// the next token comes in a second
+	ok, wait := l.Allow("a")
+	assert.False(t, ok)
+	assert.InDelta(t, time.Second, wait, float64(100*time.Millisecond))
+
+	// denied request doesn't take token
+	ok, wait2 := l.Allow("a")
+	assert.False(t, ok)
+	assert.InDelta(t, wait, wait2, float64(100*time.Millisecond))
+
+	// keys have own buckets
+	ok, _ = l.Allow("b")
+	assert.True(t, ok)
+}
+
+func TestLimiter_AllowN(t *testing.T) {
+	l := New(config.Limit{Rate: 1, Burst: 5})
+	tests := []struct {
+		name string
+		n    int
+		ok   bool
+		wait bool
+	}{
+		{name: "part of burst", n: 3, ok: true},
+		{name: "more than left", n: 3, wait: true},
+		{name: "rest of burst", n: 2, ok: true},
+		// wait is zero, because request is never allowed
+		{name: "more than burst", n: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, wait := l.AllowN("key", tt.n)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.wait, wait > 0)
+		})
+	}
+}
+
+func TestLimiter_CleanupIdleBuckets(t *testing.T) {
+	l := New(config.Limit{Rate: 1, Burst: 1})
+	l.Allow("idle")
+	l.Allow("active")
+
+	now := time.Now()
+	l.buckets["idle"].seen = now.Add(-2 * l.idleTimeout)
+	l.cleanup(now)
+	assert.NotContains(t, l.buckets, "idle")
+	assert.Contains(t, l.buckets, "active")
+}
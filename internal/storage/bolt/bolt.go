@@ -1,10 +1,14 @@
 package bolt
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
-	"fmt"
+	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	boltClient "github.com/boltdb/bolt"
@@ -14,17 +18,48 @@ import (
 )
 
 type bolt struct {
-	db        *boltClient.DB
-	bucket    []byte
-	bucketTTL []byte
+	db              *boltClient.DB
+	bucket          []byte
+	bucketTTL       []byte
+	bucketURL       []byte
+	bucketAlias     []byte
+	bucketCreated   []byte
+	bucketClicks    []byte
+	bucketTokens    []byte
+	bucketCampaigns []byte
+
+	// keyCount caches counts of bucket keys, counting walks whole buckets
+	keyCountMu      sync.Mutex
+	keyCount        map[string]int
+	keyCountUpdated time.Time
 }
 
+// keyCountTTL is lifetime of cached counts of bucket keys in Stat
+const keyCountTTL = time.Minute
+
 func New(path string, bucket string, timeout time.Duration) (*bolt, error) {
 	db, err := boltClient.Open(path, 0600, &boltClient.Options{Timeout: timeout})
 	if err != nil {
 		return nil, errors.Wrap(err, "Can't open bolt connection")
 	}
 	bucketTTL := bucket + "_ttl"
+	bucketURL := bucket + "_url"
+	bucketAlias := bucket + "_alias"
+	bucketCreated := bucket + "_created"
+	bucketClicks := bucket + "_clicks"
+	bucketTokens := bucket + "_tokens"
+	bucketCampaigns := bucket + "_campaigns"
+	b := &bolt{
+		db:              db,
+		bucket:          []byte(bucket),
+		bucketTTL:       []byte(bucketTTL),
+		bucketURL:       []byte(bucketURL),
+		bucketAlias:     []byte(bucketAlias),
+		bucketCreated:   []byte(bucketCreated),
+		bucketClicks:    []byte(bucketClicks),
+		bucketTokens:    []byte(bucketTokens),
+		bucketCampaigns: []byte(bucketCampaigns),
+	}
 	err = db.Update(func(tx *boltClient.Tx) error {
 		if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
 			return errors.Wrapf(err, "Can't create %s bucket", bucket)
@@ -32,12 +67,40 @@ func New(path string, bucket string, timeout time.Duration) (*bolt, error) {
 		if _, err := tx.CreateBucketIfNotExists([]byte(bucketTTL)); err != nil {
 			return errors.Wrapf(err, "Can't create %s bucket", bucketTTL)
 		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketAlias)); err != nil {
+			return errors.Wrapf(err, "Can't create %s bucket", bucketAlias)
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketClicks)); err != nil {
+			return errors.Wrapf(err, "Can't create %s bucket", bucketClicks)
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketTokens)); err != nil {
+			return errors.Wrapf(err, "Can't create %s bucket", bucketTokens)
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketCampaigns)); err != nil {
+			return errors.Wrapf(err, "Can't create %s bucket", bucketCampaigns)
+		}
+		if tx.Bucket([]byte(bucketURL)) == nil {
+			if _, err := tx.CreateBucket([]byte(bucketURL)); err != nil {
+				return errors.Wrapf(err, "Can't create %s bucket", bucketURL)
+			}
+			if err := b.buildIndexes(tx); err != nil {
+				return errors.Wrap(err, "Can't build indexes")
+			}
+		}
+		if tx.Bucket([]byte(bucketCreated)) == nil {
+			if _, err := tx.CreateBucket([]byte(bucketCreated)); err != nil {
+				return errors.Wrapf(err, "Can't create %s bucket", bucketCreated)
+			}
+			if err := b.buildCreatedIndex(tx); err != nil {
+				return errors.Wrapf(err, "Can't build %s index", bucketCreated)
+			}
+		}
 		return err
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "Can't create buckets")
 	}
-	return &bolt{db: db, bucket: []byte(bucket)}, nil
+	return b, nil
 }
 
 func getItemKey(decodedId uint64) string {
@@ -52,59 +115,591 @@ func (b *bolt) bucketTtl(tx *boltClient.Tx) *boltClient.Bucket {
 	return tx.Bucket(b.bucketTTL)
 }
 
+func (b *bolt) bucketUrl(tx *boltClient.Tx) *boltClient.Bucket {
+	return tx.Bucket(b.bucketURL)
+}
+
+func (b *bolt) bucketAliases(tx *boltClient.Tx) *boltClient.Bucket {
+	return tx.Bucket(b.bucketAlias)
+}
+
+func (b *bolt) bucketCreatedIndex(tx *boltClient.Tx) *boltClient.Bucket {
+	return tx.Bucket(b.bucketCreated)
+}
+
+// bucketLinkClicks returns nested bucket of clicks of link, nil if link has no clicks
+func (b *bolt) bucketLinkClicks(tx *boltClient.Tx, key []byte) *boltClient.Bucket {
+	return tx.Bucket(b.bucketClicks).Bucket(key)
+}
+
+// loadRawItem returns item by key of data bucket, nil if it is absent
+func (b *bolt) loadRawItem(tx *boltClient.Tx, key []byte) (*model.Item, error) {
+	v := b.bucketData(tx).Get(key)
+	if v == nil {
+		return nil, nil
+	}
+	var item model.Item
+	if err := json.Unmarshal(v, &item); err != nil {
+		return nil, errors.Wrapf(err, "Can't unmarshal item by key %s", key)
+	}
+	return &item, nil
+}
+
+// loadItem returns item by key of data bucket, nil if it is absent, expired or has no visits left
+func (b *bolt) loadItem(tx *boltClient.Tx, key []byte) (*model.Item, error) {
+	item, err := b.loadRawItem(tx, key)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	if item.CheckActive(time.Now()) != nil {
+		return nil, nil
+	}
+	return item, nil
+}
+
+// getUrlKey returns fixed size key of url index, because bolt limits key length
+func getUrlKey(url string) []byte {
+	sum := sha256.Sum256([]byte(url))
+	return sum[:]
+}
+
+// getTtlKey returns key of ttl index ordered by expiration time, id part allows several items per second
+func getTtlKey(expires time.Time, decodedId uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(expires.Unix()))
+	binary.BigEndian.PutUint64(key[8:], decodedId)
+	return key
+}
+
+// getCreatedKey returns key of creation time index, items created before the index have zero time
+func getCreatedKey(created time.Time, decodedId uint64) []byte {
+	key := make([]byte, 16)
+	if !created.IsZero() {
+		binary.BigEndian.PutUint64(key[:8], uint64(created.UnixNano()))
+	}
+	binary.BigEndian.PutUint64(key[8:], decodedId)
+	return key
+}
+
+// buildCreatedIndex fills creation time index by items which were created before the index appeared
+func (b *bolt) buildCreatedIndex(tx *boltClient.Tx) error {
+	bucketCreated := b.bucketCreatedIndex(tx)
+	return b.bucketData(tx).ForEach(func(k, v []byte) error {
+		var item model.Item
+		if err := json.Unmarshal(v, &item); err != nil {
+			// not an item, skip it
+			return nil
+		}
+		return bucketCreated.Put(getCreatedKey(item.Created, item.Id), k)
+	})
+}
+
+// buildIndexes fills url and ttl indexes by items which were created before the indexes appeared,
+// ttl entries stored by old versions into data bucket are removed
+func (b *bolt) buildIndexes(tx *boltClient.Tx) error {
+	bucketData := b.bucketData(tx)
+	bucketUrl := b.bucketUrl(tx)
+	bucketTtl := b.bucketTtl(tx)
+	var garbage [][]byte
+	err := bucketData.ForEach(func(k, v []byte) error {
+		var item model.Item
+		if err := json.Unmarshal(v, &item); err != nil {
+			garbage = append(garbage, k)
+			return nil
+		}
+		if item.IsReusable() {
+			if err := bucketUrl.Put(getUrlKey(item.URL), k); err != nil {
+				return err
+			}
+		}
+		if item.Expires != nil {
+			return bucketTtl.Put(getTtlKey(*item.Expires, item.Id), k)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range garbage {
+		if err := bucketData.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (b *bolt) Create(item model.Item) error {
+	err := b.db.Update(func(tx *boltClient.Tx) error {
+		return b.createItem(tx, item)
+	})
+	return errors.Wrap(err, "Can't create item")
+}
+
+// CreateBatch creates items in single transaction, duplicated items are skipped
+func (b *bolt) CreateBatch(items []model.Item) ([]error, error) {
+	errs := make([]error, len(items))
+	err := b.db.Update(func(tx *boltClient.Tx) error {
+		for i, item := range items {
+			if err := b.createItem(tx, item); err != nil {
+				if !errors.Is(err, model.ErrItemDuplicated) {
+					return err
+				}
+				errs[i] = err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't create items")
+	}
+	return errs, nil
+}
+
+// createItem puts item into buckets, duplicates are checked before any change of transaction
+func (b *bolt) createItem(tx *boltClient.Tx, item model.Item) error {
 	itemRaw, err := json.Marshal(item)
 	if err != nil {
 		return errors.Wrap(err, "Can't marshal item")
 	}
+	key := []byte(getItemKey(item.Id))
+	if b.bucketData(tx).Get(key) != nil {
+		return model.ErrItemDuplicated
+	}
+	if item.Alias != "" {
+		// alias of expired or exhausted item is held until the item is cleaned
+		if aliasKey := b.bucketAliases(tx).Get([]byte(item.Alias)); aliasKey != nil {
+			aliasItem, err := b.loadRawItem(tx, aliasKey)
+			if err != nil {
+				return err
+			}
+			if aliasItem != nil && aliasItem.Alias == item.Alias {
+				return model.ErrAliasDuplicated
+			}
+		}
+		if err := b.bucketAliases(tx).Put([]byte(item.Alias), key); err != nil {
+			return errors.Wrap(err, "Can't put data into alias bucket")
+		}
+	}
+	if err := b.bucketData(tx).Put(key, itemRaw); err != nil {
+		return errors.Wrap(err, "Can't put data into bucket")
+	}
+	// url index keeps only items, which may be found by url, so protected and limited items
+	// don't hide reusable item with the same url
+	if item.IsReusable() {
+		if err := b.bucketUrl(tx).Put(getUrlKey(item.URL), key); err != nil {
+			return errors.Wrap(err, "Can't put data into url bucket")
+		}
+	}
+	if item.Expires != nil {
+		if err := b.bucketTtl(tx).Put(getTtlKey(*item.Expires, item.Id), key); err != nil {
+			return errors.Wrap(err, "Can't put data into ttl bucket")
+		}
+	}
+	if err := b.bucketCreatedIndex(tx).Put(getCreatedKey(item.Created, item.Id), key); err != nil {
+		return errors.Wrap(err, "Can't put data into created bucket")
+	}
+	return nil
+}
 
-	isUsed := false
-	err = b.db.View(func(tx *boltClient.Tx) error {
-		v := b.bucketData(tx).Get([]byte(getItemKey(item.Id)))
-		if v != nil {
-			isUsed = true
+func (b *bolt) Find(url string) (uint64, error) {
+	var id uint64
+	err := b.db.View(func(tx *boltClient.Tx) error {
+		key := b.bucketUrl(tx).Get(getUrlKey(url))
+		if key == nil {
+			return nil
+		}
+		item, err := b.loadItem(tx, key)
+		if err != nil || item == nil || item.URL != url {
+			return err
 		}
+		id = item.Id
 		return nil
 	})
-	if isUsed {
-		return model.ErrItemDuplicated
-	}
+	return id, errors.Wrapf(err, "Can't find item by url %v", url)
+}
 
-	err = b.db.Update(func(tx *boltClient.Tx) error {
+func (b *bolt) Load(decodedId uint64) (model.Item, error) {
+	var ret model.Item
+	err := b.db.View(func(tx *boltClient.Tx) error {
+		item, err := b.loadRawItem(tx, []byte(getItemKey(decodedId)))
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			return model.ErrNoLink
+		}
+		ret = *item
+		return item.CheckActive(time.Now())
+	})
+	return ret, errors.Wrapf(err, "Can't load item %v", decodedId)
+}
+
+func (b *bolt) LoadByAlias(alias string) (model.Item, error) {
+	var ret model.Item
+	err := b.db.View(func(tx *boltClient.Tx) error {
+		key := b.bucketAliases(tx).Get([]byte(alias))
+		if key == nil {
+			return model.ErrNoLink
+		}
+		item, err := b.loadRawItem(tx, key)
+		if err != nil {
+			return err
+		}
+		if item == nil || item.Alias != alias {
+			return model.ErrNoLink
+		}
+		ret = *item
+		return item.CheckActive(time.Now())
+	})
+	return ret, errors.Wrapf(err, "Can't load item by alias %v", alias)
+}
+
+func (b *bolt) Get(decodedId uint64) (model.Item, error) {
+	var ret model.Item
+	err := b.db.View(func(tx *boltClient.Tx) error {
+		item, err := b.loadRawItem(tx, []byte(getItemKey(decodedId)))
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			return model.ErrNoLink
+		}
+		ret = *item
+		return nil
+	})
+	return ret, errors.Wrapf(err, "Can't get item %v", decodedId)
+}
+
+func (b *bolt) FindAlias(alias string) (uint64, error) {
+	var id uint64
+	err := b.db.View(func(tx *boltClient.Tx) error {
+		key := b.bucketAliases(tx).Get([]byte(alias))
+		if key == nil {
+			return nil
+		}
+		item, err := b.loadRawItem(tx, key)
+		if err != nil || item == nil || item.Alias != alias {
+			return err
+		}
+		id = item.Id
+		return nil
+	})
+	return id, errors.Wrapf(err, "Can't find item by alias %v", alias)
+}
+
+func (b *bolt) Update(item model.Item) error {
+	err := b.db.Update(func(tx *boltClient.Tx) error {
 		key := []byte(getItemKey(item.Id))
+		old, err := b.loadRawItem(tx, key)
+		if err != nil {
+			return err
+		}
+		if old == nil {
+			return model.ErrNoLink
+		}
+
+		urlKey := getUrlKey(old.URL)
+		if bytes.Equal(b.bucketUrl(tx).Get(urlKey), key) {
+			if err := b.bucketUrl(tx).Delete(urlKey); err != nil {
+				return errors.Wrap(err, "Can't delete from url bucket")
+			}
+		}
+		if old.Expires != nil {
+			if err := b.bucketTtl(tx).Delete(getTtlKey(*old.Expires, old.Id)); err != nil {
+				return errors.Wrap(err, "Can't delete from ttl bucket")
+			}
+		}
+
+		old.URL = item.URL
+		old.Expires = item.Expires
+		old.ActiveFrom = item.ActiveFrom
+		old.ExpiredURL = item.ExpiredURL
+		old.RedirectType = item.RedirectType
+		old.QueryPolicy = item.QueryPolicy
+		old.QueryAllow = item.QueryAllow
+		old.Campaign = item.Campaign
+		old.DeviceRules = item.DeviceRules
+		itemRaw, err := json.Marshal(old)
+		if err != nil {
+			return errors.Wrap(err, "Can't marshal item")
+		}
 		if err := b.bucketData(tx).Put(key, itemRaw); err != nil {
 			return errors.Wrap(err, "Can't put data into bucket")
 		}
-		if item.Expires != nil {
-			ttlKey := []byte(strconv.FormatInt(item.Expires.Unix(), 10))
-			if err := b.bucketData(tx).Put(ttlKey, key); err != nil {
+		if old.IsReusable() {
+			if err := b.bucketUrl(tx).Put(getUrlKey(old.URL), key); err != nil {
+				return errors.Wrap(err, "Can't put data into url bucket")
+			}
+		}
+		if old.Expires != nil {
+			if err := b.bucketTtl(tx).Put(getTtlKey(*old.Expires, old.Id), key); err != nil {
 				return errors.Wrap(err, "Can't put data into ttl bucket")
 			}
 		}
 		return nil
 	})
-	return errors.Wrap(err, "Can't create item")
+	return errors.Wrapf(err, "Can't update item %v", item.Id)
 }
 
-func (b *bolt) Find(url string) (uint64, error) {
-	return 0, fmt.Errorf("Not implemented")
+func (b *bolt) Visit(decodedId uint64) error {
+	err := b.db.Update(func(tx *boltClient.Tx) error {
+		key := []byte(getItemKey(decodedId))
+		item, err := b.loadItem(tx, key)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			return model.ErrNoLink
+		}
+		if item.MaxVisits == 0 {
+			return nil
+		}
+		item.Visits++
+		itemRaw, err := json.Marshal(item)
+		if err != nil {
+			return errors.Wrap(err, "Can't marshal item")
+		}
+		return errors.Wrap(b.bucketData(tx).Put(key, itemRaw), "Can't put data into bucket")
+	})
+	return errors.Wrapf(err, "Can't visit item %v", decodedId)
 }
 
-func (b *bolt) Load(decodedId uint64) (string, error) {
-	var url string
-	err := b.db.View(func(tx *boltClient.Tx) error {
-		v := b.bucketData(tx).Get([]byte(getItemKey(decodedId)))
-		if v == nil {
+func (b *bolt) Delete(decodedId uint64) error {
+	err := b.db.Update(func(tx *boltClient.Tx) error {
+		key := []byte(getItemKey(decodedId))
+		item, err := b.loadRawItem(tx, key)
+		if err != nil {
+			return err
+		}
+		if item == nil {
 			return model.ErrNoLink
 		}
-		var item model.Item
-		if err := json.Unmarshal(v, &item); err != nil {
-			return errors.Wrapf(err, "Can't found item by key %v", decodedId)
+		return b.deleteItem(tx, key, *item)
+	})
+	return errors.Wrapf(err, "Can't delete item %v", decodedId)
+}
+
+// deleteItem removes item with all its index entries
+func (b *bolt) deleteItem(tx *boltClient.Tx, key []byte, item model.Item) error {
+	urlKey := getUrlKey(item.URL)
+	if bytes.Equal(b.bucketUrl(tx).Get(urlKey), key) {
+		if err := b.bucketUrl(tx).Delete(urlKey); err != nil {
+			return errors.Wrap(err, "Can't delete from url bucket")
+		}
+	}
+	if item.Alias != "" && bytes.Equal(b.bucketAliases(tx).Get([]byte(item.Alias)), key) {
+		if err := b.bucketAliases(tx).Delete([]byte(item.Alias)); err != nil {
+			return errors.Wrap(err, "Can't delete from alias bucket")
+		}
+	}
+	if item.Expires != nil {
+		if err := b.bucketTtl(tx).Delete(getTtlKey(*item.Expires, item.Id)); err != nil {
+			return errors.Wrap(err, "Can't delete from ttl bucket")
+		}
+	}
+	if err := b.bucketCreatedIndex(tx).Delete(getCreatedKey(item.Created, item.Id)); err != nil {
+		return errors.Wrap(err, "Can't delete from created bucket")
+	}
+	if b.bucketLinkClicks(tx, key) != nil {
+		if err := tx.Bucket(b.bucketClicks).DeleteBucket(key); err != nil {
+			return errors.Wrap(err, "Can't delete from clicks bucket")
+		}
+	}
+	return errors.Wrap(b.bucketData(tx).Delete(key), "Can't delete from bucket")
+}
+
+func (b *bolt) List(query model.ListQuery) ([]model.Item, error) {
+	var items []model.Item
+	err := b.db.View(func(tx *boltClient.Tx) error {
+		c := b.bucketCreatedIndex(tx).Cursor()
+
+		var k, v []byte
+		next := c.Next
+		switch {
+		case query.Desc && query.Cursor == nil:
+			k, v = c.Last()
+			next = c.Prev
+		case query.Desc:
+			cursorKey := getCreatedKey(query.Cursor.Created, query.Cursor.Id)
+			if k, v = c.Seek(cursorKey); k == nil {
+				k, v = c.Last()
+			}
+			for k != nil && bytes.Compare(k, cursorKey) >= 0 {
+				k, v = c.Prev()
+			}
+			next = c.Prev
+		case query.Cursor == nil:
+			k, v = c.First()
+		default:
+			cursorKey := getCreatedKey(query.Cursor.Created, query.Cursor.Id)
+			if k, v = c.Seek(cursorKey); bytes.Equal(k, cursorKey) {
+				k, v = c.Next()
+			}
+		}
+
+		for ; k != nil && len(items) < query.Limit; k, v = next() {
+			item, err := b.loadRawItem(tx, v)
+			if err != nil {
+				return err
+			}
+			if item != nil && query.Matches(*item) {
+				items = append(items, *item)
+			}
+		}
+		return nil
+	})
+	return items, errors.Wrap(err, "Can't list items")
+}
+
+func (b *bolt) SaveClicks(clicks []model.Click) error {
+	err := b.db.Update(func(tx *boltClient.Tx) error {
+		for _, click := range clicks {
+			key := []byte(getItemKey(click.LinkId))
+			if b.bucketData(tx).Get(key) == nil {
+				// link was deleted after redirect
+				continue
+			}
+			bucket, err := tx.Bucket(b.bucketClicks).CreateBucketIfNotExists(key)
+			if err != nil {
+				return errors.Wrap(err, "Can't create link clicks bucket")
+			}
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return errors.Wrap(err, "Can't get click sequence")
+			}
+			clickRaw, err := json.Marshal(click)
+			if err != nil {
+				return errors.Wrap(err, "Can't marshal click")
+			}
+			clickKey := make([]byte, 8)
+			binary.BigEndian.PutUint64(clickKey, seq)
+			if err := bucket.Put(clickKey, clickRaw); err != nil {
+				return errors.Wrap(err, "Can't put data into clicks bucket")
+			}
 		}
-		url = item.URL
 		return nil
 	})
-	return url, errors.Wrapf(err, "Can't load item %v", decodedId)
+	return errors.Wrap(err, "Can't save clicks")
+}
+
+func (b *bolt) ClickStat(decodedId uint64) (model.ClickStat, error) {
+	var clicks []model.Click
+	err := b.db.View(func(tx *boltClient.Tx) error {
+		bucket := b.bucketLinkClicks(tx, []byte(getItemKey(decodedId)))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var click model.Click
+			if err := json.Unmarshal(v, &click); err != nil {
+				return errors.Wrapf(err, "Can't unmarshal click %x", k)
+			}
+			clicks = append(clicks, click)
+			return nil
+		})
+	})
+	if err != nil {
+		return model.ClickStat{}, errors.Wrapf(err, "Can't load clicks %v", decodedId)
+	}
+	return model.AggregateClicks(clicks), nil
+}
+
+func (b *bolt) SaveToken(token model.Token) error {
+	tokenRaw, err := json.Marshal(token)
+	if err != nil {
+		return errors.Wrap(err, "Can't marshal token")
+	}
+	err = b.db.Update(func(tx *boltClient.Tx) error {
+		bucket := tx.Bucket(b.bucketTokens)
+		if bucket.Get([]byte(token.Name)) != nil {
+			return model.ErrTokenDuplicated
+		}
+		return bucket.Put([]byte(token.Name), tokenRaw)
+	})
+	return errors.Wrap(err, "Can't save token")
+}
+
+func (b *bolt) DeleteToken(name string) error {
+	err := b.db.Update(func(tx *boltClient.Tx) error {
+		bucket := tx.Bucket(b.bucketTokens)
+		if bucket.Get([]byte(name)) == nil {
+			return model.ErrNoToken
+		}
+		return bucket.Delete([]byte(name))
+	})
+	return errors.Wrap(err, "Can't delete token")
+}
+
+func (b *bolt) ListTokens() ([]model.Token, error) {
+	tokens := make([]model.Token, 0)
+	err := b.db.View(func(tx *boltClient.Tx) error {
+		return tx.Bucket(b.bucketTokens).ForEach(func(k, v []byte) error {
+			var token model.Token
+			if err := json.Unmarshal(v, &token); err != nil {
+				return errors.Wrapf(err, "Can't unmarshal token %s", k)
+			}
+			tokens = append(tokens, token)
+			return nil
+		})
+	})
+	return tokens, errors.Wrap(err, "Can't list tokens")
+}
+
+// SaveCampaign creates or replaces campaign, creation time of replaced campaign is kept
+func (b *bolt) SaveCampaign(campaign model.Campaign) error {
+	err := b.db.Update(func(tx *boltClient.Tx) error {
+		bucket := tx.Bucket(b.bucketCampaigns)
+		if oldRaw := bucket.Get([]byte(campaign.Name)); oldRaw != nil {
+			var old model.Campaign
+			if err := json.Unmarshal(oldRaw, &old); err != nil {
+				return errors.Wrapf(err, "Can't unmarshal campaign %s", campaign.Name)
+			}
+			campaign.Created = old.Created
+		}
+		campaignRaw, err := json.Marshal(campaign)
+		if err != nil {
+			return errors.Wrap(err, "Can't marshal campaign")
+		}
+		return bucket.Put([]byte(campaign.Name), campaignRaw)
+	})
+	return errors.Wrap(err, "Can't save campaign")
+}
+
+func (b *bolt) DeleteCampaign(name string) error {
+	err := b.db.Update(func(tx *boltClient.Tx) error {
+		bucket := tx.Bucket(b.bucketCampaigns)
+		if bucket.Get([]byte(name)) == nil {
+			return model.ErrNoCampaign
+		}
+		return bucket.Delete([]byte(name))
+	})
+	return errors.Wrap(err, "Can't delete campaign")
+}
+
+func (b *bolt) ListCampaigns() ([]model.Campaign, error) {
+	campaigns := make([]model.Campaign, 0)
+	err := b.db.View(func(tx *boltClient.Tx) error {
+		return tx.Bucket(b.bucketCampaigns).ForEach(func(k, v []byte) error {
+			var campaign model.Campaign
+			if err := json.Unmarshal(v, &campaign); err != nil {
+				return errors.Wrapf(err, "Can't unmarshal campaign %s", k)
+			}
+			campaigns = append(campaigns, campaign)
+			return nil
+		})
+	})
+	return campaigns, errors.Wrap(err, "Can't list campaigns")
+}
+
+// NextSequence uses sequence of data bucket
+func (b *bolt) NextSequence() (uint64, error) {
+	var value uint64
+	err := b.db.Update(func(tx *boltClient.Tx) error {
+		var err error
+		value, err = b.bucketData(tx).NextSequence()
+		return err
+	})
+	return value, errors.Wrap(err, "Can't get next value of sequence")
 }
 
 func (b *bolt) Close() error {
@@ -112,5 +707,62 @@ func (b *bolt) Close() error {
 }
 
 func (b *bolt) Stat(ctx context.Context) (any, error) {
-	return "Not implemented", nil
+	fileInfo, err := os.Stat(b.db.Path())
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't stat bolt file")
+	}
+
+	keyCount, err := b.countKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	s := b.db.Stats()
+	return struct {
+		FileSize      int64          `json:"fileSize"`
+		TxN           int            `json:"txN"`
+		OpenTxN       int            `json:"openTxN"`
+		FreePageN     int            `json:"freePageN"`
+		PendingPageN  int            `json:"pendingPageN"`
+		FreeAlloc     int            `json:"freeAlloc"`
+		FreelistInuse int            `json:"freelistInuse"`
+		WriteCount    int            `json:"writeCount"`
+		WriteTime     time.Duration  `json:"writeTime"`
+		KeyCount      map[string]int `json:"keyCount"`
+	}{
+		FileSize:      fileInfo.Size(),
+		TxN:           s.TxN,
+		OpenTxN:       s.OpenTxN,
+		FreePageN:     s.FreePageN,
+		PendingPageN:  s.PendingPageN,
+		FreeAlloc:     s.FreeAlloc,
+		FreelistInuse: s.FreelistInuse,
+		WriteCount:    s.TxStats.Write,
+		WriteTime:     s.TxStats.WriteTime,
+		KeyCount:      keyCount,
+	}, nil
+}
+
+// countKeys returns counts of bucket keys, they are cached for keyCountTTL, so health checks don't walk
+// the whole database every time
+func (b *bolt) countKeys() (map[string]int, error) {
+	b.keyCountMu.Lock()
+	defer b.keyCountMu.Unlock()
+
+	if b.keyCount != nil && time.Since(b.keyCountUpdated) < keyCountTTL {
+		return b.keyCount, nil
+	}
+	keyCount := make(map[string]int)
+	err := b.db.View(func(tx *boltClient.Tx) error {
+		for _, name := range [][]byte{b.bucket, b.bucketTTL, b.bucketURL, b.bucketAlias, b.bucketCreated, b.bucketTokens, b.bucketCampaigns} {
+			keyCount[string(name)] = tx.Bucket(name).Stats().KeyN
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't count bucket keys")
+	}
+	b.keyCount = keyCount
+	b.keyCountUpdated = time.Now()
+	return keyCount, nil
 }
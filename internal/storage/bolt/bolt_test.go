@@ -0,0 +1,87 @@
+package bolt
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+func newTestBolt(t *testing.T) *bolt {
+	b, err := New(filepath.Join(t.TempDir(), "bolt.db"), "links", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+	return b
+}
+
+func TestBolt_FindSkipsNotReusable(t *testing.T) {
+	const url = "http://example.com/"
+	tests := []struct {
+		name  string
+		other model.Item
+	}{
+		{name: "protected", other: model.Item{Id: 2, URL: url, PasswordHash: "hash"}},
+		{name: "limited", other: model.Item{Id: 2, URL: url, MaxVisits: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBolt(t)
+			if !assert.NoError(t, b.Create(model.Item{Id: 1, URL: url, Created: time.Now()})) {
+				return
+			}
+
+			tt.other.Created = time.Now()
+			if !assert.NoError(t, b.Create(tt.other)) {
+				return
+			}
+			id, err := b.Find(url)
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(1), id, "reusable item is found after creation")
+
+			tt.other.URL = url
+			assert.NoError(t, b.Update(tt.other))
+			id, err = b.Find(url)
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(1), id, "reusable item is found after update")
+
+			// deletion of not indexed item keeps index
+			assert.NoError(t, b.Delete(tt.other.Id))
+			id, err = b.Find(url)
+			assert.NoError(t, err)
+			assert.Equal(t, uint64(1), id, "reusable item is found after deletion")
+		})
+	}
+}
+
+func TestBolt_FindOnlyNotReusable(t *testing.T) {
+	b := newTestBolt(t)
+	const url = "http://example.com/"
+	assert.NoError(t, b.Create(model.Item{Id: 1, URL: url, MaxVisits: 1, Created: time.Now()}))
+
+	id, err := b.Find(url)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), id)
+}
+
+func TestBolt_CountKeysIsCached(t *testing.T) {
+	b := newTestBolt(t)
+	assert.NoError(t, b.Create(model.Item{Id: 1, URL: "http://example.com/1", Created: time.Now()}))
+	keyCount, err := b.countKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, keyCount["links"])
+
+	assert.NoError(t, b.Create(model.Item{Id: 2, URL: "http://example.com/2", Created: time.Now()}))
+	keyCount, err = b.countKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, keyCount["links"], "counts are cached")
+
+	b.keyCountUpdated = time.Now().Add(-keyCountTTL)
+	keyCount, err = b.countKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, keyCount["links"], "counts are refreshed after ttl")
+}
@@ -1,6 +1,61 @@
 package bolt
 
-func (b *bolt) CleanExpired() error {
-	// TODO
-	return nil
+import (
+	"encoding/binary"
+	"time"
+
+	boltClient "github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+const cleanBatchSize = 1000
+
+// CleanExpired deletes items expired before the time
+func (b *bolt) CleanExpired(before time.Time) error {
+	for {
+		count, err := b.cleanExpiredBatch(uint64(before.Unix()))
+		if err != nil {
+			return errors.Wrap(err, "Can't delete expires links")
+		}
+		if count < cleanBatchSize {
+			return nil
+		}
+	}
+}
+
+// cleanExpiredBatch deletes up to cleanBatchSize items expired before the unix time in one transaction
+func (b *bolt) cleanExpiredBatch(before uint64) (int, error) {
+	count := 0
+	err := b.db.Update(func(tx *boltClient.Tx) error {
+		bucketTtl := b.bucketTtl(tx)
+
+		var ttlKeys, dataKeys [][]byte
+		c := bucketTtl.Cursor()
+		for k, v := c.First(); k != nil && len(ttlKeys) < cleanBatchSize; k, v = c.Next() {
+			if binary.BigEndian.Uint64(k[:8]) > before {
+				break
+			}
+			// copy keys, they are valid only until the first modification of transaction
+			ttlKeys = append(ttlKeys, append([]byte(nil), k...))
+			dataKeys = append(dataKeys, append([]byte(nil), v...))
+		}
+
+		for i, key := range dataKeys {
+			item, err := b.loadRawItem(tx, key)
+			if err != nil {
+				return err
+			}
+			if item != nil {
+				if err := b.deleteItem(tx, key, *item); err != nil {
+					return err
+				}
+			}
+			if err := bucketTtl.Delete(ttlKeys[i]); err != nil {
+				return errors.Wrap(err, "Can't delete from ttl bucket")
+			}
+		}
+		count = len(ttlKeys)
+		return nil
+	})
+	return count, err
 }
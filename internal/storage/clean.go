@@ -6,13 +6,14 @@ import (
 	"time"
 )
 
-func startCleanScheduler(ctx context.Context, c clientCleaner) {
+// startCleanScheduler deletes items hourly, when grace period after their expiration passes
+func startCleanScheduler(ctx context.Context, c clientCleaner, grace time.Duration) {
 	ticker := time.NewTicker(time.Hour)
 	log.Infoln("Started expired items cleaner")
 	for {
 		select {
 		case <-ticker.C:
-			if err := c.CleanExpired(); err != nil {
+			if err := c.CleanExpired(time.Now().Add(-grace)); err != nil {
 				log.Errorf("Can't clean expires: %+v", err)
 			}
 		case <-ctx.Done():
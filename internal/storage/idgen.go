@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/base62"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/feistel"
+)
+
+// IDGenerator returns candidates for id of new item, it must be safe for concurrent use
+type IDGenerator interface {
+	NextID() (uint64, error)
+}
+
+// randomGenerator draws ids uniformly from inclusive range with crypto random source,
+// so replicas never share a sequence
+type randomGenerator struct {
+	min  uint64
+	size uint64
+}
+
+// NewRandomGenerator returns generator of ids from min to max inclusive, min must be positive,
+// because zero id means absent item in storage lookups
+func NewRandomGenerator(min, max uint64) IDGenerator {
+	return randomGenerator{min: min, size: max - min + 1}
+}
+
+func (g randomGenerator) NextID() (uint64, error) {
+	// values above the largest multiple of size are rejected to keep distribution uniform,
+	// zero size means the whole uint64 range
+	limit := uint64(math.MaxUint64)
+	if g.size != 0 {
+		limit = math.MaxUint64 - (math.MaxUint64%g.size+1)%g.size
+	}
+	var buf [8]byte
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return 0, errors.Wrap(err, "Can't read random id")
+		}
+		value := binary.BigEndian.Uint64(buf[:])
+		if value > limit {
+			continue
+		}
+		if g.size != 0 {
+			value %= g.size
+		}
+		if id := g.min + value; id != 0 {
+			return id, nil
+		}
+	}
+}
+
+type sequencer interface {
+	NextSequence() (uint64, error)
+}
+
+// sequentialGenerator takes ids from storage sequence, so there are no collisions,
+// ids fill codes of minimal length at first and are shuffled by keyed permutation inside each length
+type sequentialGenerator struct {
+	sequence    sequencer
+	codec       *base62.Codec
+	permutation *feistel.Permutation
+}
+
+func NewSequentialGenerator(sequence sequencer, codec *base62.Codec, key string) IDGenerator {
+	return sequentialGenerator{sequence: sequence, codec: codec, permutation: feistel.New(key)}
+}
+
+func (g sequentialGenerator) NextID() (uint64, error) {
+	value, err := g.sequence.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+	offset := value - 1
+	for length := max(g.codec.Length(), 1); ; length++ {
+		first, last := g.codec.LengthRange(length)
+		if first == 0 {
+			return 0, errors.New("Sequence is exhausted")
+		}
+		size := last - first + 1
+		if offset < size {
+			return first + g.permutation.Permute(offset, size), nil
+		}
+		offset -= size
+	}
+}
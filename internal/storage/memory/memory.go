@@ -0,0 +1,440 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+type memory struct {
+	mu        sync.RWMutex
+	path      string
+	items     map[uint64]model.Item
+	urls      map[string]uint64
+	aliases   map[string]uint64
+	clicks    map[uint64][]model.Click
+	sequence  uint64
+	tokens    map[string]model.Token
+	campaigns map[string]model.Campaign
+}
+
+type snapshot struct {
+	Items     []model.Item             `json:"items"`
+	Clicks    map[uint64][]model.Click `json:"clicks"`
+	Sequence  uint64                   `json:"sequence"`
+	Tokens    []model.Token            `json:"tokens"`
+	Campaigns []model.Campaign         `json:"campaigns"`
+}
+
+// New creates in-memory storage, if path is not empty the snapshot is loaded from it and written back on Close
+func New(path string) (*memory, error) {
+	m := &memory{
+		path:      path,
+		items:     make(map[uint64]model.Item),
+		urls:      make(map[string]uint64),
+		aliases:   make(map[string]uint64),
+		clicks:    make(map[uint64][]model.Click),
+		tokens:    make(map[string]model.Token),
+		campaigns: make(map[string]model.Campaign),
+	}
+	if path == "" {
+		return m, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, errors.Wrapf(err, "Can't read snapshot %v", path)
+	}
+	var data snapshot
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, errors.Wrapf(err, "Can't unmarshal snapshot %v", path)
+	}
+	for id, clicks := range data.Clicks {
+		m.clicks[id] = clicks
+	}
+	m.sequence = data.Sequence
+	for _, token := range data.Tokens {
+		m.tokens[token.Name] = token
+	}
+	for _, campaign := range data.Campaigns {
+		m.campaigns[campaign.Name] = campaign
+	}
+	for _, item := range data.Items {
+		m.items[item.Id] = item
+		if item.IsReusable() {
+			m.urls[item.URL] = item.Id
+		}
+		if item.Alias != "" {
+			m.aliases[item.Alias] = item.Id
+		}
+	}
+	return m, nil
+}
+
+func isExpired(item model.Item, now time.Time) bool {
+	return item.Expires != nil && item.Expires.Before(now)
+}
+
+func isActive(item model.Item, now time.Time) bool {
+	return item.CheckActive(now) == nil
+}
+
+func (m *memory) Create(item model.Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.items[item.Id]; ok {
+		return model.ErrItemDuplicated
+	}
+	if item.Alias != "" {
+		// alias of expired or exhausted item is held until the item is cleaned
+		if _, ok := m.aliases[item.Alias]; ok {
+			return model.ErrAliasDuplicated
+		}
+		m.aliases[item.Alias] = item.Id
+	}
+	m.items[item.Id] = item
+	// protected and limited items don't hide reusable item with the same url
+	if item.IsReusable() {
+		m.urls[item.URL] = item.Id
+	}
+	return nil
+}
+
+func (m *memory) CreateBatch(items []model.Item) ([]error, error) {
+	errs := make([]error, len(items))
+	for i, item := range items {
+		errs[i] = m.Create(item)
+	}
+	return errs, nil
+}
+
+func (m *memory) Find(url string) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, ok := m.urls[url]
+	if !ok {
+		return 0, nil
+	}
+	item, ok := m.items[id]
+	if !ok || !isActive(item, time.Now()) {
+		return 0, nil
+	}
+	return id, nil
+}
+
+func (m *memory) Load(decodedId uint64) (model.Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.items[decodedId]
+	if !ok {
+		return model.Item{}, model.ErrNoLink
+	}
+	if err := item.CheckActive(time.Now()); err != nil {
+		return model.Item{}, err
+	}
+	return item, nil
+}
+
+func (m *memory) LoadByAlias(alias string) (model.Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.items[m.aliases[alias]]
+	if !ok || item.Alias != alias {
+		return model.Item{}, model.ErrNoLink
+	}
+	if err := item.CheckActive(time.Now()); err != nil {
+		return model.Item{}, err
+	}
+	return item, nil
+}
+
+func (m *memory) Get(decodedId uint64) (model.Item, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, ok := m.items[decodedId]
+	if !ok {
+		return model.Item{}, model.ErrNoLink
+	}
+	return item, nil
+}
+
+func (m *memory) FindAlias(alias string) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.aliases[alias], nil
+}
+
+func (m *memory) Update(item model.Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, ok := m.items[item.Id]
+	if !ok {
+		return model.ErrNoLink
+	}
+	if m.urls[old.URL] == item.Id {
+		delete(m.urls, old.URL)
+	}
+	old.URL = item.URL
+	old.Expires = item.Expires
+	old.ActiveFrom = item.ActiveFrom
+	old.ExpiredURL = item.ExpiredURL
+	old.RedirectType = item.RedirectType
+	old.QueryPolicy = item.QueryPolicy
+	old.QueryAllow = item.QueryAllow
+	old.Campaign = item.Campaign
+	old.DeviceRules = item.DeviceRules
+	m.items[item.Id] = old
+	if old.IsReusable() {
+		m.urls[old.URL] = item.Id
+	}
+	return nil
+}
+
+func (m *memory) Visit(decodedId uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[decodedId]
+	if !ok || !isActive(item, time.Now()) {
+		return model.ErrNoLink
+	}
+	if item.MaxVisits > 0 {
+		item.Visits++
+		m.items[decodedId] = item
+	}
+	return nil
+}
+
+func (m *memory) Delete(decodedId uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[decodedId]
+	if !ok {
+		return model.ErrNoLink
+	}
+	m.delete(item)
+	return nil
+}
+
+// delete removes item with its indexes, the caller must hold the write lock
+func (m *memory) delete(item model.Item) {
+	delete(m.items, item.Id)
+	if m.urls[item.URL] == item.Id {
+		delete(m.urls, item.URL)
+	}
+	if item.Alias != "" && m.aliases[item.Alias] == item.Id {
+		delete(m.aliases, item.Alias)
+	}
+	delete(m.clicks, item.Id)
+}
+
+func (m *memory) List(query model.ListQuery) ([]model.Item, error) {
+	m.mu.RLock()
+	items := make([]model.Item, 0, len(m.items))
+	for _, item := range m.items {
+		if query.After(item) && query.Matches(item) {
+			items = append(items, item)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if query.Desc {
+			a, b = b, a
+		}
+		if a.Created.Equal(b.Created) {
+			return a.Id < b.Id
+		}
+		return a.Created.Before(b.Created)
+	})
+	if len(items) > query.Limit {
+		items = items[:query.Limit]
+	}
+	return items, nil
+}
+
+func (m *memory) SaveClicks(clicks []model.Click) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, click := range clicks {
+		if _, ok := m.items[click.LinkId]; ok {
+			m.clicks[click.LinkId] = append(m.clicks[click.LinkId], click)
+		}
+	}
+	return nil
+}
+
+func (m *memory) ClickStat(decodedId uint64) (model.ClickStat, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return model.AggregateClicks(m.clicks[decodedId]), nil
+}
+
+func (m *memory) CleanExpired(before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, item := range m.items {
+		if isExpired(item, before) {
+			m.delete(item)
+		}
+	}
+	return nil
+}
+
+func (m *memory) NextSequence() (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sequence++
+	return m.sequence, nil
+}
+
+func (m *memory) SaveToken(token model.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tokens[token.Name]; ok {
+		return model.ErrTokenDuplicated
+	}
+	m.tokens[token.Name] = token
+	return nil
+}
+
+func (m *memory) DeleteToken(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tokens[name]; !ok {
+		return model.ErrNoToken
+	}
+	delete(m.tokens, name)
+	return nil
+}
+
+func (m *memory) ListTokens() ([]model.Token, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tokens := make([]model.Token, 0, len(m.tokens))
+	for _, token := range m.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens, nil
+}
+
+// SaveCampaign creates or replaces campaign, creation time of replaced campaign is kept
+func (m *memory) SaveCampaign(campaign model.Campaign) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if old, ok := m.campaigns[campaign.Name]; ok {
+		campaign.Created = old.Created
+	}
+	m.campaigns[campaign.Name] = campaign
+	return nil
+}
+
+func (m *memory) DeleteCampaign(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.campaigns[name]; !ok {
+		return model.ErrNoCampaign
+	}
+	delete(m.campaigns, name)
+	return nil
+}
+
+func (m *memory) ListCampaigns() ([]model.Campaign, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	campaigns := make([]model.Campaign, 0, len(m.campaigns))
+	for _, campaign := range m.campaigns {
+		campaigns = append(campaigns, campaign)
+	}
+	sort.Slice(campaigns, func(i, j int) bool {
+		return campaigns[i].Name < campaigns[j].Name
+	})
+	return campaigns, nil
+}
+
+// snapshot writes items into temporary file and renames it, so the previous snapshot is never left broken
+func (m *memory) snapshot() error {
+	m.mu.RLock()
+	data := snapshot{
+		Items:     make([]model.Item, 0, len(m.items)),
+		Clicks:    m.clicks,
+		Sequence:  m.sequence,
+		Tokens:    make([]model.Token, 0, len(m.tokens)),
+		Campaigns: make([]model.Campaign, 0, len(m.campaigns)),
+	}
+	for _, token := range m.tokens {
+		data.Tokens = append(data.Tokens, token)
+	}
+	for _, campaign := range m.campaigns {
+		data.Campaigns = append(data.Campaigns, campaign)
+	}
+	for _, item := range m.items {
+		data.Items = append(data.Items, item)
+	}
+	b, err := json.Marshal(data)
+	m.mu.RUnlock()
+
+	if err != nil {
+		return errors.Wrap(err, "Can't marshal snapshot")
+	}
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0600); err != nil {
+		return errors.Wrapf(err, "Can't write snapshot %v", tmpPath)
+	}
+	return errors.Wrapf(os.Rename(tmpPath, m.path), "Can't rename snapshot %v", tmpPath)
+}
+
+func (m *memory) Close() error {
+	if m.path == "" {
+		return nil
+	}
+	return m.snapshot()
+}
+
+func (m *memory) Stat(ctx context.Context) (any, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return struct {
+		ItemCount  int    `json:"itemCount"`
+		UrlCount   int    `json:"urlCount"`
+		AliasCount int    `json:"aliasCount"`
+		Snapshot   string `json:"snapshot"`
+	}{
+		ItemCount:  len(m.items),
+		UrlCount:   len(m.urls),
+		AliasCount: len(m.aliases),
+		Snapshot:   m.path,
+	}, nil
+}
@@ -5,7 +5,11 @@ import (
 	"time"
 )
 
-func (pg *Psql) CleanExpired() error {
-	err := pg.exec("DELETE FROM links WHERE expires IS NOT NULL AND expires < $1", time.Now())
+// CleanExpired deletes items expired before the time
+func (pg *Psql) CleanExpired(before time.Time) error {
+	err := pg.exec(`
+		WITH deleted AS (DELETE FROM links WHERE expires IS NOT NULL AND expires < $1 RETURNING id)
+		DELETE FROM clicks WHERE link_id IN (SELECT id FROM deleted)
+	`, before)
 	return errors.Wrap(err, "Can't delete expires links")
 }
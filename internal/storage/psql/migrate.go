@@ -18,6 +18,51 @@ func migrate(ctx context.Context, conn *pgxpool.Conn) error {
 	if err := migrationV3(ctx, conn); err != nil {
 		return err
 	}
+	if err := migrationV4(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV5(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV6(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV7(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV8(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV9(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV10(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV11(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV12(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV13(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV14(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV15(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV16(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV17(ctx, conn); err != nil {
+		return err
+	}
+	if err := migrationV18(ctx, conn); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -110,3 +155,431 @@ func migrationV3(ctx context.Context, conn *pgxpool.Conn) error {
 
 	return nil
 }
+
+func migrationV4(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "alias",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V4...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links ADD COLUMN alias VARCHAR
+	`); err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(ctx, `
+		CREATE UNIQUE INDEX links_alias_uniq ON public.links (alias) WHERE alias IS NOT NULL
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV5(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "created",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V5...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links ADD COLUMN created TIMESTAMPTZ NOT NULL DEFAULT now()
+	`); err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(ctx, `
+		CREATE INDEX links_created_idx ON public.links (created, id)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV6(ctx context.Context, conn *pgxpool.Conn) error {
+	var tableExists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", "public.clicks").Scan(&tableExists); err != nil {
+		return err
+	}
+
+	if tableExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V6...")
+
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE public.clicks (
+			link_id BIGINT NOT NULL,
+			time TIMESTAMPTZ NOT NULL,
+			referrer VARCHAR NOT NULL,
+			user_agent VARCHAR NOT NULL,
+			country VARCHAR NOT NULL,
+			cache_hit BOOLEAN NOT NULL
+		)
+	`); err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(ctx, `
+		CREATE INDEX clicks_link_id_time_idx ON public.clicks (link_id, time)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV7(ctx context.Context, conn *pgxpool.Conn) error {
+	var sequenceExists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", "public.links_seq").Scan(&sequenceExists); err != nil {
+		return err
+	}
+
+	if sequenceExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V7...")
+
+	if _, err := conn.Exec(ctx, `
+		CREATE SEQUENCE public.links_seq AS BIGINT
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV8(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "owner",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V8...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links ADD COLUMN owner VARCHAR NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(ctx, `
+		CREATE INDEX links_owner_created_idx ON public.links (owner, created, id)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV9(ctx context.Context, conn *pgxpool.Conn) error {
+	var tableExists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", "public.tokens").Scan(&tableExists); err != nil {
+		return err
+	}
+
+	if tableExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V9...")
+
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE public.tokens (
+			name VARCHAR PRIMARY KEY,
+			hash VARCHAR NOT NULL,
+			scopes TEXT[] NOT NULL,
+			created TIMESTAMPTZ NOT NULL
+		)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV10(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "password_hash",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V10...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links ADD COLUMN password_hash VARCHAR NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV11(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "max_visits",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V11...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links
+			ADD COLUMN max_visits BIGINT NOT NULL DEFAULT 0,
+			ADD COLUMN visits BIGINT NOT NULL DEFAULT 0
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV12(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "active_from",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V12...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links ADD COLUMN active_from TIMESTAMPTZ
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV13(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "expired_url",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V13...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links ADD COLUMN expired_url VARCHAR NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV14(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "redirect_type",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V14...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links ADD COLUMN redirect_type SMALLINT NOT NULL DEFAULT 0
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV15(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "query_allow",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V15...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links
+			ADD COLUMN query_policy VARCHAR NOT NULL DEFAULT '',
+			ADD COLUMN query_allow TEXT[]
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV16(ctx context.Context, conn *pgxpool.Conn) error {
+	var tableExists bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", "public.campaigns").Scan(&tableExists); err != nil {
+		return err
+	}
+
+	if tableExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V16...")
+
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE public.campaigns (
+			name VARCHAR PRIMARY KEY,
+			params JSONB NOT NULL,
+			created TIMESTAMPTZ NOT NULL
+		)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV17(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "campaign",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V17...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links ADD COLUMN campaign VARCHAR NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(ctx, `
+		CREATE INDEX links_campaign_idx ON public.links (campaign, created, id) WHERE campaign != ''
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV18(ctx context.Context, conn *pgxpool.Conn) error {
+	var columnExists bool
+	if err := conn.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = $1 AND column_name = $2)",
+		"links", "device_rules",
+	).Scan(&columnExists); err != nil {
+		return err
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	log.Infoln("Postgresql migrates V18...")
+
+	if _, err := conn.Exec(ctx, `
+		ALTER TABLE public.links ADD COLUMN device_rules JSONB
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
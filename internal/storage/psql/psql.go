@@ -3,6 +3,7 @@ package psql
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -67,24 +68,96 @@ func (pg *Psql) queryRow(sql string, args ...interface{}) (pgx.Row, error) {
 	return row, nil
 }
 
+// nullIfEmpty converts empty string to NULL, so unique index ignores it
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+const insertItem = "INSERT INTO links (id, url, alias, expires, created, owner, password_hash, max_visits, active_from, expired_url, " +
+	"redirect_type, query_policy, query_allow, campaign, device_rules) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)"
+
+func itemArgs(item model.Item) []any {
+	return []any{
+		int64(item.Id), item.URL, nullIfEmpty(item.Alias), item.Expires, item.Created,
+		item.Owner, item.PasswordHash, item.MaxVisits, item.ActiveFrom, item.ExpiredURL, item.RedirectType,
+		item.QueryPolicy, item.QueryAllow, item.Campaign, item.DeviceRules,
+	}
+}
+
 func (pg *Psql) Create(item model.Item) error {
-	err := pg.exec(
-		"INSERT INTO links (id, url, expires) VALUES ($1, $2, $3)",
-		int64(item.Id), item.URL, item.Expires,
-	)
+	err := pg.exec(insertItem, itemArgs(item)...)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == "23505" && pgErr.ConstraintName == "links_id_uniq" {
 				return model.ErrItemDuplicated
 			}
+			if pgErr.Code == "23505" && pgErr.ConstraintName == "links_alias_uniq" {
+				return model.ErrAliasDuplicated
+			}
 		}
 	}
 	return err
 }
 
+// CreateBatch inserts items by single batch, conflicting items are skipped and
+// their ids are checked afterwards to tell id collision from taken alias
+func (pg *Psql) CreateBatch(items []model.Item) ([]error, error) {
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		batch.Queue(insertItem+" ON CONFLICT DO NOTHING RETURNING id", itemArgs(item)...)
+	}
+	results := pg.pool.SendBatch(pg.ctx, batch)
+	var conflicted []int
+	for i := range items {
+		var id int64
+		if err := results.QueryRow().Scan(&id); err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				_ = results.Close()
+				return nil, errors.Wrap(err, "Can't insert items")
+			}
+			conflicted = append(conflicted, i)
+		}
+	}
+	if err := results.Close(); err != nil {
+		return nil, errors.Wrap(err, "Can't insert items")
+	}
+
+	errs := make([]error, len(items))
+	if len(conflicted) == 0 {
+		return errs, nil
+	}
+	ids := make([]int64, 0, len(conflicted))
+	for _, i := range conflicted {
+		ids = append(ids, int64(items[i].Id))
+	}
+	rows, err := pg.pool.Query(pg.ctx, "SELECT id FROM links WHERE id = ANY($1)", ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't query conflicted ids")
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't scan conflicted ids")
+	}
+	collided := make(map[int64]bool, len(existing))
+	for _, id := range existing {
+		collided[id] = true
+	}
+	for _, i := range conflicted {
+		if collided[int64(items[i].Id)] {
+			errs[i] = model.ErrItemDuplicated
+		} else {
+			errs[i] = model.ErrAliasDuplicated
+		}
+	}
+	return errs, nil
+}
+
 func (pg *Psql) Find(url string) (uint64, error) {
-	row, _ := pg.queryRow("SELECT id FROM links WHERE url = $1", url)
+	row, _ := pg.queryRow("SELECT id FROM links WHERE url = $1 AND password_hash = '' AND max_visits = 0", url)
 	var id int64
 	if err := row.Scan(&id); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -95,21 +168,295 @@ func (pg *Psql) Find(url string) (uint64, error) {
 	return uint64(id), nil
 }
 
-func (pg *Psql) Load(decodedId uint64) (string, error) {
-	var url string
-	var expires *time.Time
-	row, _ := pg.queryRow("SELECT url, expires FROM links WHERE id = $1", int64(decodedId))
-	if err := row.Scan(&url, &expires); err != nil {
+func (pg *Psql) Load(decodedId uint64) (model.Item, error) {
+	row, _ := pg.queryRow("SELECT "+itemColumns+" FROM links WHERE id = $1", int64(decodedId))
+	item, err := scanActiveItem(row)
+	if err != nil && !errors.Is(err, model.ErrNoLink) {
+		return model.Item{}, errors.Wrapf(err, "Can't scan item %v", int64(decodedId))
+	}
+	return item, err
+}
+
+func (pg *Psql) LoadByAlias(alias string) (model.Item, error) {
+	row, _ := pg.queryRow("SELECT "+itemColumns+" FROM links WHERE alias = $1", alias)
+	item, err := scanActiveItem(row)
+	if err != nil && !errors.Is(err, model.ErrNoLink) {
+		return model.Item{}, errors.Wrapf(err, "Can't scan item %v", alias)
+	}
+	return item, err
+}
+
+const itemColumns = "id, url, COALESCE(alias, ''), expires, created, owner, password_hash, max_visits, visits, active_from, expired_url, " +
+	"redirect_type, query_policy, query_allow, campaign, device_rules"
+
+func scanItem(row pgx.Row) (model.Item, error) {
+	var item model.Item
+	var id int64
+	if err := row.Scan(&id, &item.URL, &item.Alias, &item.Expires, &item.Created, &item.Owner, &item.PasswordHash,
+		&item.MaxVisits, &item.Visits, &item.ActiveFrom, &item.ExpiredURL,
+		&item.RedirectType, &item.QueryPolicy, &item.QueryAllow, &item.Campaign, &item.DeviceRules,
+	); err != nil {
+		return model.Item{}, err
+	}
+	item.Id = uint64(id)
+	return item, nil
+}
+
+// scanActiveItem returns item from row, model.ErrNoLink if row is absent, *model.ExpiredError if it is expired
+// or has no visits left
+func scanActiveItem(row pgx.Row) (model.Item, error) {
+	item, err := scanItem(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Item{}, model.ErrNoLink
+		}
+		return model.Item{}, err
+	}
+	if err := item.CheckActive(time.Now()); err != nil {
+		return model.Item{}, err
+	}
+	return item, nil
+}
+
+func (pg *Psql) Get(decodedId uint64) (model.Item, error) {
+	row, _ := pg.queryRow("SELECT "+itemColumns+" FROM links WHERE id = $1", int64(decodedId))
+	item, err := scanItem(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Item{}, model.ErrNoLink
+		}
+		return model.Item{}, errors.Wrapf(err, "Can't scan item %v", int64(decodedId))
+	}
+	return item, nil
+}
+
+func (pg *Psql) FindAlias(alias string) (uint64, error) {
+	row, _ := pg.queryRow("SELECT id FROM links WHERE alias = $1", alias)
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, errors.Wrapf(err, "Can't scan id %v", alias)
+	}
+	return uint64(id), nil
+}
+
+// execAffected executes sql and returns model.ErrNoLink if no row was affected
+func (pg *Psql) execAffected(sql string, args ...interface{}) error {
+	tag, err := pg.pool.Exec(pg.ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNoLink
+	}
+	return nil
+}
+
+func (pg *Psql) Update(item model.Item) error {
+	return pg.execAffected(
+		"UPDATE links SET url = $2, expires = $3, active_from = $4, expired_url = $5, redirect_type = $6, "+
+			"query_policy = $7, query_allow = $8, campaign = $9, device_rules = $10 WHERE id = $1",
+		int64(item.Id), item.URL, item.Expires, item.ActiveFrom, item.ExpiredURL, item.RedirectType,
+		item.QueryPolicy, item.QueryAllow, item.Campaign, item.DeviceRules,
+	)
+}
+
+// Visit counts visit of link with visit limit by single statement, so concurrent visits can't exceed the limit
+func (pg *Psql) Visit(decodedId uint64) error {
+	row, _ := pg.queryRow(`
+		UPDATE links SET visits = visits + CASE WHEN max_visits > 0 THEN 1 ELSE 0 END
+		WHERE id = $1 AND (expires IS NULL OR expires >= now()) AND (max_visits = 0 OR visits < max_visits)
+		RETURNING visits`,
+		int64(decodedId),
+	)
+	var visits int64
+	if err := row.Scan(&visits); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return "", model.ErrNoLink
+			return model.ErrNoLink
+		}
+		return errors.Wrapf(err, "Can't visit item %v", int64(decodedId))
+	}
+	return nil
+}
+
+func (pg *Psql) Delete(decodedId uint64) error {
+	if err := pg.execAffected("DELETE FROM links WHERE id = $1", int64(decodedId)); err != nil {
+		return err
+	}
+	return errors.Wrap(pg.exec("DELETE FROM clicks WHERE link_id = $1", int64(decodedId)), "Can't delete clicks")
+}
+
+func (pg *Psql) List(query model.ListQuery) ([]model.Item, error) {
+	op, order := ">", "ASC"
+	if query.Desc {
+		op, order = "<", "DESC"
+	}
+	sql := "SELECT " + itemColumns + " FROM links"
+	var conditions []string
+	var args []interface{}
+	if query.Cursor != nil {
+		args = append(args, query.Cursor.Created, int64(query.Cursor.Id))
+		conditions = append(conditions, fmt.Sprintf("(created, id) %s ($%d, $%d)", op, len(args)-1, len(args)))
+	}
+	if query.Owner != "" {
+		args = append(args, query.Owner)
+		conditions = append(conditions, fmt.Sprintf("owner = $%d", len(args)))
+	}
+	if query.Campaign != "" {
+		args = append(args, query.Campaign)
+		conditions = append(conditions, fmt.Sprintf("campaign = $%d", len(args)))
+	}
+	if len(conditions) > 0 {
+		sql += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	sql += fmt.Sprintf(" ORDER BY created %[1]s, id %[1]s LIMIT %[2]d", order, query.Limit)
+
+	rows, err := pg.pool.Query(pg.ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't query items")
+	}
+	defer rows.Close()
+
+	var items []model.Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't scan item")
 		}
-		return "", errors.Wrapf(err, "Can't scan url and expires %v", int64(decodedId))
+		items = append(items, item)
 	}
-	if expires != nil && expires.Local().UTC().Before(time.Now().UTC()) {
-		return "", model.ErrNoLink
+	return items, errors.Wrap(rows.Err(), "Can't read items")
+}
+
+func (pg *Psql) SaveClicks(clicks []model.Click) error {
+	_, err := pg.pool.CopyFrom(
+		pg.ctx,
+		pgx.Identifier{"clicks"},
+		[]string{"link_id", "time", "referrer", "user_agent", "country", "cache_hit"},
+		pgx.CopyFromSlice(len(clicks), func(i int) ([]any, error) {
+			c := clicks[i]
+			return []any{int64(c.LinkId), c.Time, c.Referrer, c.UserAgent, c.Country, c.CacheHit}, nil
+		}),
+	)
+	return errors.Wrap(err, "Can't copy clicks")
+}
+
+func (pg *Psql) ClickStat(decodedId uint64) (model.ClickStat, error) {
+	stat := model.ClickStat{Daily: []model.DailyClicks{}}
+	rows, err := pg.pool.Query(pg.ctx, `
+		SELECT to_char(time AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, count(*), count(*) FILTER (WHERE cache_hit)
+		FROM clicks WHERE link_id = $1 GROUP BY day ORDER BY day
+	`, int64(decodedId))
+	if err != nil {
+		return stat, errors.Wrap(err, "Can't query clicks")
 	}
+	defer rows.Close()
 
-	return url, nil
+	for rows.Next() {
+		var day model.DailyClicks
+		var cacheHits int64
+		if err := rows.Scan(&day.Date, &day.Count, &cacheHits); err != nil {
+			return stat, errors.Wrap(err, "Can't scan clicks")
+		}
+		stat.Total += day.Count
+		stat.CacheHits += cacheHits
+		stat.Daily = append(stat.Daily, day)
+	}
+	return stat, errors.Wrap(rows.Err(), "Can't read clicks")
+}
+
+func (pg *Psql) SaveToken(token model.Token) error {
+	_, err := pg.pool.Exec(pg.ctx,
+		"INSERT INTO tokens (name, hash, scopes, created) VALUES ($1, $2, $3, $4)",
+		token.Name, token.Hash, token.Scopes, token.Created,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return model.ErrTokenDuplicated
+		}
+		return errors.Wrap(err, "Can't insert token")
+	}
+	return nil
+}
+
+func (pg *Psql) DeleteToken(name string) error {
+	tag, err := pg.pool.Exec(pg.ctx, "DELETE FROM tokens WHERE name = $1", name)
+	if err != nil {
+		return errors.Wrap(err, "Can't delete token")
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNoToken
+	}
+	return nil
+}
+
+func (pg *Psql) ListTokens() ([]model.Token, error) {
+	rows, err := pg.pool.Query(pg.ctx, "SELECT name, hash, scopes, created FROM tokens ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't query tokens")
+	}
+	defer rows.Close()
+
+	tokens := make([]model.Token, 0)
+	for rows.Next() {
+		var token model.Token
+		if err := rows.Scan(&token.Name, &token.Hash, &token.Scopes, &token.Created); err != nil {
+			return nil, errors.Wrap(err, "Can't scan token")
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, errors.Wrap(rows.Err(), "Can't read tokens")
+}
+
+// SaveCampaign creates or replaces campaign, creation time of replaced campaign is kept
+func (pg *Psql) SaveCampaign(campaign model.Campaign) error {
+	_, err := pg.pool.Exec(pg.ctx,
+		"INSERT INTO campaigns (name, params, created) VALUES ($1, $2, $3) "+
+			"ON CONFLICT (name) DO UPDATE SET params = excluded.params",
+		campaign.Name, campaign.Params, campaign.Created,
+	)
+	return errors.Wrap(err, "Can't save campaign")
+}
+
+func (pg *Psql) DeleteCampaign(name string) error {
+	tag, err := pg.pool.Exec(pg.ctx, "DELETE FROM campaigns WHERE name = $1", name)
+	if err != nil {
+		return errors.Wrap(err, "Can't delete campaign")
+	}
+	if tag.RowsAffected() == 0 {
+		return model.ErrNoCampaign
+	}
+	return nil
+}
+
+func (pg *Psql) ListCampaigns() ([]model.Campaign, error) {
+	rows, err := pg.pool.Query(pg.ctx, "SELECT name, params, created FROM campaigns ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't query campaigns")
+	}
+	defer rows.Close()
+
+	campaigns := make([]model.Campaign, 0)
+	for rows.Next() {
+		var campaign model.Campaign
+		if err := rows.Scan(&campaign.Name, &campaign.Params, &campaign.Created); err != nil {
+			return nil, errors.Wrap(err, "Can't scan campaign")
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, errors.Wrap(rows.Err(), "Can't read campaigns")
+}
+
+func (pg *Psql) NextSequence() (uint64, error) {
+	row, _ := pg.queryRow("SELECT nextval('public.links_seq')")
+	var value int64
+	if err := row.Scan(&value); err != nil {
+		return 0, errors.Wrap(err, "Can't get next value of sequence")
+	}
+	return uint64(value), nil
 }
 
 func (pg *Psql) Close() error {
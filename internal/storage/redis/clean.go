@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+
+	redisClient "github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+// cleanBatchSize is count of expired links, which are read from expiration index by one request
+const cleanBatchSize = 1000
+
+// expiresBuiltKey marks expiration index as filled by links, which were created before the index appeared
+const expiresBuiltKey = "links:expires:built"
+
+// CleanExpired deletes items expired before the time with their index entries and clicks, most items are
+// already expired by redis, because their keys expire at the end of grace period
+func (r *redis) CleanExpired(before time.Time) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	if err := r.buildExpiresIndex(conn); err != nil {
+		return err
+	}
+	// scores are times of keys expiration, which are grace period later than expiration of items
+	maxScore := "(" + strconv.FormatInt(r.expireAt(before), 10)
+	for {
+		members, err := redisClient.Strings(conn.Do("ZRANGEBYSCORE", expiresKey, "-inf", maxScore, "LIMIT", 0, cleanBatchSize))
+		if err != nil {
+			return errors.Wrap(err, "Can't get expired links")
+		}
+		for _, member := range members {
+			id, err := strconv.ParseUint(member, 10, 64)
+			if err != nil {
+				return errors.Wrapf(err, "Can't parse expires member %v", member)
+			}
+			if err := r.cleanItem(conn, id, member); err != nil {
+				return err
+			}
+		}
+		if len(members) < cleanBatchSize {
+			return nil
+		}
+	}
+}
+
+// cleanItem deletes expired item, index entries and clicks of item already expired by redis are deleted too
+func (r *redis) cleanItem(conn redisClient.Conn, id uint64, member string) error {
+	if err := r.delete(conn, id); err != nil && !errors.Is(err, model.ErrNoLink) {
+		return errors.Wrapf(err, "Can't delete expired link %v", id)
+	}
+	if _, err := conn.Do("ZREM", createdKey, member); err != nil {
+		return errors.Wrapf(err, "Can't delete created member %v", member)
+	}
+	if _, err := conn.Do("ZREM", expiresKey, member); err != nil {
+		return errors.Wrapf(err, "Can't delete expires member %v", member)
+	}
+	return r.deleteClicks(conn, id)
+}
+
+// buildExpiresIndex fills expiration index by links, which were created before the index appeared, once,
+// index entries and clicks of links already expired by redis are deleted
+func (r *redis) buildExpiresIndex(conn redisClient.Conn) error {
+	built, err := redisClient.Bool(conn.Do("EXISTS", expiresBuiltKey))
+	if err != nil {
+		return errors.Wrap(err, "Can't check expires index")
+	}
+	if built {
+		return nil
+	}
+
+	cursor := 0
+	for {
+		values, err := redisClient.Values(conn.Do("ZSCAN", createdKey, cursor, "COUNT", cleanBatchSize))
+		if err != nil {
+			return errors.Wrap(err, "Can't scan created index")
+		}
+		var entries []string
+		if _, err := redisClient.Scan(values, &cursor, &entries); err != nil {
+			return errors.Wrap(err, "Can't parse created index")
+		}
+		// entries are pairs of member and score
+		for i := 0; i < len(entries); i += 2 {
+			member := entries[i]
+			id, err := strconv.ParseUint(member, 10, 64)
+			if err != nil {
+				return errors.Wrapf(err, "Can't parse created member %v", member)
+			}
+			if err := r.indexExpires(conn, id, member); err != nil {
+				return err
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	_, err = conn.Do("SET", expiresBuiltKey, 1)
+	return errors.Wrap(err, "Can't mark expires index as built")
+}
+
+// indexExpires adds item to expiration index, item already expired by redis is cleaned
+func (r *redis) indexExpires(conn redisClient.Conn, id uint64, member string) error {
+	var item Item
+	expires, err := redisClient.String(conn.Do("HGET", getItemKey(id), "expires"))
+	if errors.Is(err, redisClient.ErrNil) {
+		return r.cleanItem(conn, id, member)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Can't get expiration of link %v", id)
+	}
+	item.Expires = expires
+	t := item.ExportExpires()
+	if t == nil {
+		return nil
+	}
+	if _, err := conn.Do("ZADD", expiresKey, r.expireAt(*t), member); err != nil {
+		return errors.Wrapf(err, "Can't add expires member %v", member)
+	}
+	return nil
+}
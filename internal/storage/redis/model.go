@@ -1,28 +1,115 @@
 package redis
 
 import (
+	"strings"
 	"time"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
 )
 
 type Item struct {
-	Id      uint64 `redis:"id"`
-	URL     string `redis:"url"`
-	Expires string `redis:"expires"`
+	Id           uint64 `redis:"id"`
+	URL          string `redis:"url"`
+	Alias        string `redis:"alias"`
+	Expires      string `redis:"expires"`
+	Created      string `redis:"created"`
+	Owner        string `redis:"owner"`
+	PasswordHash string `redis:"password_hash"`
+	MaxVisits    int64  `redis:"max_visits"`
+	Visits       int64  `redis:"visits"`
+	ActiveFrom   string `redis:"active_from"`
+	ExpiredURL   string `redis:"expired_url"`
+	RedirectType int    `redis:"redirect_type"`
+	QueryPolicy  string `redis:"query_policy"`
+	// QueryAllow is comma separated names of parameters
+	QueryAllow string `redis:"query_allow"`
+	Campaign   string `redis:"campaign"`
+	// DeviceRules is json of rules
+	DeviceRules string `redis:"device_rules"`
 }
 
-func (i *Item) ExportExpires() *time.Time {
-	if i.Expires == "" {
+// exportTime parses optional time, empty string is nil time
+func exportTime(val string) *time.Time {
+	if val == "" {
 		return nil
 	}
-	ret, _ := time.Parse(time.RFC3339, i.Expires)
+	ret, _ := time.Parse(time.RFC3339, val)
 	return &ret
 }
 
-func (i *Item) ImportExpires(val *time.Time) {
+// importTime formats optional time, nil time is empty string
+func importTime(val *time.Time) string {
 	if val == nil {
-		i.Expires = ""
-		return
+		return ""
+	}
+	return val.Format(time.RFC3339)
+}
+
+func (i *Item) ExportExpires() *time.Time {
+	return exportTime(i.Expires)
+}
+
+func (i *Item) ImportExpires(val *time.Time) {
+	i.Expires = importTime(val)
+}
+
+func (i *Item) ExportActiveFrom() *time.Time {
+	return exportTime(i.ActiveFrom)
+}
+
+func (i *Item) ImportActiveFrom(val *time.Time) {
+	i.ActiveFrom = importTime(val)
+}
+
+func (i *Item) ExportQueryAllow() []string {
+	if i.QueryAllow == "" {
+		return nil
+	}
+	return strings.Split(i.QueryAllow, ",")
+}
+
+func (i *Item) ImportQueryAllow(val []string) {
+	i.QueryAllow = strings.Join(val, ",")
+}
+
+func (i *Item) ExportDeviceRules() []model.DeviceRule {
+	ret, _ := model.DecodeDeviceRules(i.DeviceRules)
+	return ret
+}
+
+func (i *Item) ImportDeviceRules(val []model.DeviceRule) {
+	i.DeviceRules = model.EncodeDeviceRules(val)
+}
+
+func (i *Item) ExportCreated() time.Time {
+	if i.Created == "" {
+		return time.Time{}
+	}
+	ret, _ := time.Parse(time.RFC3339Nano, i.Created)
+	return ret
+}
+
+func (i *Item) ImportCreated(val time.Time) {
+	i.Created = val.Format(time.RFC3339Nano)
+}
+
+func (i *Item) Export() model.Item {
+	return model.Item{
+		Id:           i.Id,
+		URL:          i.URL,
+		Alias:        i.Alias,
+		Expires:      i.ExportExpires(),
+		Created:      i.ExportCreated(),
+		Owner:        i.Owner,
+		PasswordHash: i.PasswordHash,
+		MaxVisits:    i.MaxVisits,
+		Visits:       i.Visits,
+		ActiveFrom:   i.ExportActiveFrom(),
+		ExpiredURL:   i.ExpiredURL,
+		RedirectType: i.RedirectType,
+		QueryPolicy:  i.QueryPolicy,
+		QueryAllow:   i.ExportQueryAllow(),
+		Campaign:     i.Campaign,
+		DeviceRules:  i.ExportDeviceRules(),
 	}
-	exp := val.Format(time.RFC3339)
-	i.Expires = exp
 }
@@ -2,8 +2,13 @@ package redis
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	redisClient "github.com/gomodule/redigo/redis"
@@ -14,19 +19,84 @@ import (
 
 const errorDuplicate = "Duplicate"
 
+const errorDuplicateAlias = "DuplicateAlias"
+
+const errorNoLink = "NoLink"
+
+// sequenceKey is counter of sequential ids
+const sequenceKey = "links:sequence"
+
+// tokensKey is hash of api tokens by name
+const tokensKey = "tokens"
+
+// campaignsKey is hash of campaigns by name
+const campaignsKey = "campaigns"
+
+// clicksMaxLen is approximate limit of click events kept in stream of link
+const clicksMaxLen = 10000
+
+// createdKey is sorted set of links ordered by creation time in milliseconds
+const createdKey = "links:created"
+
+// expiresKey is sorted set of links with expiration ordered by unix time of their keys expiration,
+// members are the same as in createdKey
+const expiresKey = "links:expires"
+
 const checkAndSetScript = `
 local key = KEYS[1]
-local id = ARGV[2]
-local url = ARGV[3]
-local expires = ARGV[4]
+local urlKey = KEYS[2]
+local createdKey = KEYS[3]
+local expiresKey = KEYS[4]
+local aliasKey = KEYS[5]
+local id = ARGV[1]
+local url = ARGV[2]
+local alias = ARGV[3]
+local created = ARGV[4]
+local createdScore = ARGV[5]
+local createdMember = ARGV[6]
+local expiresValue = ARGV[7]
+local owner = ARGV[8]
+local passwordHash = ARGV[9]
+local maxVisits = ARGV[10]
+local activeFrom = ARGV[11]
+local expiredUrl = ARGV[12]
+local redirectType = ARGV[13]
+local queryPolicy = ARGV[14]
+local queryAllow = ARGV[15]
+local campaign = ARGV[16]
+local deviceRules = ARGV[17]
+local expires = ARGV[18]
 
 local exists = redis.call('EXISTS', key)
 
 if exists == 0 then
-    redis.call('HMSET', key, 'id', id, 'url', url)
+    if aliasKey and redis.call('EXISTS', aliasKey) == 1 then
+        return "` + errorDuplicateAlias + `"
+    end
+
+    redis.call('HMSET', key, 'id', id, 'url', url, 'alias', alias, 'created', created, 'expires', expiresValue, 'owner', owner,
+        'password_hash', passwordHash, 'max_visits', maxVisits, 'visits', 0,
+        'active_from', activeFrom, 'expired_url', expiredUrl, 'redirect_type', redirectType,
+        'query_policy', queryPolicy, 'query_allow', queryAllow, 'campaign', campaign, 'device_rules', deviceRules)
+    -- protected and limited items don't hide reusable item with the same url
+    local reusable = passwordHash == '' and maxVisits == '0'
+    if reusable then
+        redis.call('SET', urlKey, id)
+    end
+    if aliasKey then
+        redis.call('SET', aliasKey, id)
+    end
+    redis.call('ZADD', createdKey, createdScore, createdMember)
 
     if expires then
+        redis.call('ZADD', expiresKey, expires, createdMember)
         redis.call('EXPIREAT', key, expires)
+        if reusable then
+            redis.call('EXPIREAT', urlKey, expires)
+        end
+        if aliasKey then
+            redis.call('EXPIREAT', aliasKey, expires)
+        end
     end
 
     return "Ok"
@@ -35,11 +105,116 @@ else
 end
 `
 
+const updateScript = `
+local key = KEYS[1]
+local oldUrlKey = KEYS[2]
+local urlKey = KEYS[3]
+local expiresKey = KEYS[4]
+local aliasKey = KEYS[5]
+local id = ARGV[1]
+local url = ARGV[2]
+local expiresValue = ARGV[3]
+local activeFrom = ARGV[4]
+local expiredUrl = ARGV[5]
+local redirectType = ARGV[6]
+local queryPolicy = ARGV[7]
+local queryAllow = ARGV[8]
+local campaign = ARGV[9]
+local deviceRules = ARGV[10]
+local createdMember = ARGV[11]
+local expires = ARGV[12]
+
+if redis.call('EXISTS', key) == 0 then
+    return "` + errorNoLink + `"
+end
+
+redis.call('HMSET', key, 'url', url, 'expires', expiresValue, 'active_from', activeFrom, 'expired_url', expiredUrl,
+    'redirect_type', redirectType, 'query_policy', queryPolicy, 'query_allow', queryAllow, 'campaign', campaign,
+    'device_rules', deviceRules)
+if redis.call('GET', oldUrlKey) == id then
+    redis.call('DEL', oldUrlKey)
+end
+local keys = {key}
+-- fields are absent in items created by old versions
+local passwordHash = redis.call('HGET', key, 'password_hash') or ''
+local maxVisits = redis.call('HGET', key, 'max_visits') or '0'
+if passwordHash == '' and maxVisits == '0' then
+    redis.call('SET', urlKey, id)
+    table.insert(keys, urlKey)
+end
+if aliasKey then
+    table.insert(keys, aliasKey)
+end
+if expires then
+    redis.call('ZADD', expiresKey, expires, createdMember)
+else
+    redis.call('ZREM', expiresKey, createdMember)
+end
+for _, k in ipairs(keys) do
+    if expires then
+        redis.call('EXPIREAT', k, expires)
+    else
+        redis.call('PERSIST', k)
+    end
+end
+
+return "Ok"
+`
+
+// visitScript counts visit of link with visit limit, it fails when no visits are left
+const visitScript = `
+local key = KEYS[1]
+
+if redis.call('EXISTS', key) == 0 then
+    return "` + errorNoLink + `"
+end
+
+local maxVisits = tonumber(redis.call('HGET', key, 'max_visits')) or 0
+if maxVisits == 0 then
+    return "Ok"
+end
+local visits = tonumber(redis.call('HGET', key, 'visits')) or 0
+if visits >= maxVisits then
+    return "` + errorNoLink + `"
+end
+redis.call('HINCRBY', key, 'visits', 1)
+
+return "Ok"
+`
+
+const deleteScript = `
+local key = KEYS[1]
+local urlKey = KEYS[2]
+local createdKey = KEYS[3]
+local expiresKey = KEYS[4]
+local aliasKey = KEYS[5]
+local id = ARGV[1]
+local createdMember = ARGV[2]
+
+if redis.call('EXISTS', key) == 0 then
+    return "` + errorNoLink + `"
+end
+
+redis.call('DEL', key)
+if redis.call('GET', urlKey) == id then
+    redis.call('DEL', urlKey)
+end
+if aliasKey and redis.call('GET', aliasKey) == id then
+    redis.call('DEL', aliasKey)
+end
+redis.call('ZREM', createdKey, createdMember)
+redis.call('ZREM', expiresKey, createdMember)
+
+return "Ok"
+`
+
 type redis struct {
 	pool *redisClient.Pool
+	// grace is period, during which expired keys are kept
+	grace time.Duration
 }
 
-func New(host string, port int, password string) (*redis, error) {
+func New(host string, port int, password string, grace time.Duration) (*redis, error) {
 	pool := &redisClient.Pool{
 		MaxIdle:     10,
 		IdleTimeout: 240 * time.Second,
@@ -48,57 +223,634 @@ func New(host string, port int, password string) (*redis, error) {
 		},
 	}
 
-	return &redis{pool}, nil
+	return &redis{pool: pool, grace: grace}, nil
+}
+
+// expireAt returns unix time of keys expiration, they live for grace period after item expiration
+func (r *redis) expireAt(expires time.Time) int64 {
+	return expires.Add(r.grace).Unix()
 }
 
 func getItemKey(id uint64) string {
 	return "link:" + strconv.FormatUint(id, 10)
 }
 
+// getUrlKey returns key of reverse index from url hash to id
+func getUrlKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return "url:" + hex.EncodeToString(sum[:])
+}
+
+func getAliasKey(alias string) string {
+	return "alias:" + alias
+}
+
+// getClicksKey returns key of stream of link click events
+func getClicksKey(id uint64) string {
+	return "clicks:" + strconv.FormatUint(id, 10)
+}
+
+// getClickStatKey returns key of hash of link click counters: total, cacheHits and day:<date>
+func getClickStatKey(id uint64) string {
+	return "clicks:stat:" + strconv.FormatUint(id, 10)
+}
+
+// getCreatedMember returns member of created sorted set, it is padded to be ordered as number
+func getCreatedMember(id uint64) string {
+	return fmt.Sprintf("%020d", id)
+}
+
+func getCreatedScore(created time.Time) int64 {
+	if created.IsZero() {
+		return 0
+	}
+	return created.UnixMilli()
+}
+
+// createArgs returns arguments of EVAL of check and set script for item
+func (r *redis) createArgs(item model.Item) []any {
+	keys := []any{getItemKey(item.Id), getUrlKey(item.URL), createdKey, expiresKey}
+	if item.Alias != "" {
+		keys = append(keys, getAliasKey(item.Alias))
+	}
+	var redisItem Item
+	redisItem.ImportCreated(item.Created)
+	redisItem.ImportExpires(item.Expires)
+	redisItem.ImportActiveFrom(item.ActiveFrom)
+	redisItem.ImportQueryAllow(item.QueryAllow)
+	redisItem.ImportDeviceRules(item.DeviceRules)
+	args := []any{checkAndSetScript, len(keys)}
+	args = append(args, keys...)
+	args = append(args,
+		item.Id, item.URL, item.Alias,
+		redisItem.Created, getCreatedScore(item.Created), getCreatedMember(item.Id),
+		redisItem.Expires, item.Owner, item.PasswordHash, item.MaxVisits, redisItem.ActiveFrom, item.ExpiredURL,
+		item.RedirectType, item.QueryPolicy, redisItem.QueryAllow, item.Campaign, redisItem.DeviceRules,
+	)
+	if item.Expires != nil {
+		args = append(args, r.expireAt(*item.Expires))
+	}
+	return args
+}
+
+// createResult converts result of check and set script to error
+func createResult(result string) error {
+	if result == errorDuplicate {
+		return model.ErrItemDuplicated
+	}
+	if result == errorDuplicateAlias {
+		return model.ErrAliasDuplicated
+	}
+	return nil
+}
+
 func (r *redis) Create(item model.Item) (err error) {
 	conn := r.pool.Get()
 	defer conn.Close()
 
-	args := []any{
-		checkAndSetScript, 1, getItemKey(item.Id), item.Id, item.URL,
+	result, err := redisClient.String(conn.Do("EVAL", r.createArgs(item)...))
+	if err != nil {
+		return errors.Wrap(err, "Error executing Lua script for check and set item")
+	}
+	return createResult(result)
+}
+
+// CreateBatch pipelines check and set scripts of items, each item is created atomically by itself
+func (r *redis) CreateBatch(items []model.Item) ([]error, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	for _, item := range items {
+		if err := conn.Send("EVAL", r.createArgs(item)...); err != nil {
+			return nil, errors.Wrap(err, "Can't send Lua script for check and set item")
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, errors.Wrap(err, "Can't flush Lua scripts for check and set items")
+	}
+	errs := make([]error, len(items))
+	for i := range items {
+		result, err := redisClient.String(conn.Receive())
+		if err != nil {
+			return nil, errors.Wrap(err, "Error executing Lua script for check and set item")
+		}
+		errs[i] = createResult(result)
+	}
+	return errs, nil
+}
+
+func (r *redis) Find(url string) (uint64, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	id, err := redisClient.Uint64(conn.Do("GET", getUrlKey(url)))
+	if err != nil {
+		if errors.Is(err, redisClient.ErrNil) {
+			return 0, nil
+		}
+		return 0, errors.Wrapf(err, "Can't get id by url %v", url)
+	}
+
+	itemUrl, err := redisClient.String(conn.Do("HGET", getItemKey(id), "url"))
+	if err != nil {
+		if errors.Is(err, redisClient.ErrNil) {
+			return 0, nil
+		}
+		return 0, errors.Wrapf(err, "Can't get url by id %v", id)
 	}
+	if itemUrl != url {
+		return 0, nil
+	}
+
+	return id, nil
+}
+
+func (r *redis) Load(decodedId uint64) (model.Item, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	item, err := r.get(conn, decodedId)
+	if err != nil {
+		return model.Item{}, err
+	}
+	if len(item.URL) == 0 {
+		return model.Item{}, model.ErrNoLink
+	}
+	if err := item.CheckActive(time.Now()); err != nil {
+		return model.Item{}, err
+	}
+	return item, nil
+}
+
+func (r *redis) LoadByAlias(alias string) (model.Item, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	id, err := redisClient.Uint64(conn.Do("GET", getAliasKey(alias)))
+	if err != nil {
+		if errors.Is(err, redisClient.ErrNil) {
+			return model.Item{}, model.ErrNoLink
+		}
+		return model.Item{}, errors.Wrapf(err, "Can't get id by alias %v", alias)
+	}
+
+	item, err := r.get(conn, id)
+	if err != nil {
+		return model.Item{}, err
+	}
+	if err := item.CheckActive(time.Now()); err != nil {
+		return model.Item{}, err
+	}
+	return item, nil
+}
+
+func (r *redis) get(conn redisClient.Conn, decodedId uint64) (model.Item, error) {
+	values, err := redisClient.Values(conn.Do("HGETALL", getItemKey(decodedId)))
+	if err != nil {
+		return model.Item{}, errors.Wrapf(err, "Can't get item %v", decodedId)
+	}
+	if len(values) == 0 {
+		return model.Item{}, model.ErrNoLink
+	}
+	var item Item
+	if err := redisClient.ScanStruct(values, &item); err != nil {
+		return model.Item{}, errors.Wrapf(err, "Can't scan item %v", decodedId)
+	}
+	return item.Export(), nil
+}
+
+func (r *redis) Get(decodedId uint64) (model.Item, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return r.get(conn, decodedId)
+}
+
+func (r *redis) FindAlias(alias string) (uint64, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	id, err := redisClient.Uint64(conn.Do("GET", getAliasKey(alias)))
+	if err != nil {
+		if errors.Is(err, redisClient.ErrNil) {
+			return 0, nil
+		}
+		return 0, errors.Wrapf(err, "Can't get id by alias %v", alias)
+	}
+	return id, nil
+}
+
+func (r *redis) Update(item model.Item) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	old, err := r.get(conn, item.Id)
+	if err != nil {
+		return err
+	}
+
+	keys := []any{getItemKey(item.Id), getUrlKey(old.URL), getUrlKey(item.URL), expiresKey}
+	if old.Alias != "" {
+		keys = append(keys, getAliasKey(old.Alias))
+	}
+	var redisItem Item
+	redisItem.ImportExpires(item.Expires)
+	redisItem.ImportActiveFrom(item.ActiveFrom)
+	redisItem.ImportQueryAllow(item.QueryAllow)
+	redisItem.ImportDeviceRules(item.DeviceRules)
+	args := []any{updateScript, len(keys)}
+	args = append(args, keys...)
+	args = append(args, item.Id, item.URL, redisItem.Expires, redisItem.ActiveFrom, item.ExpiredURL, item.RedirectType,
+		item.QueryPolicy, redisItem.QueryAllow, item.Campaign, redisItem.DeviceRules, getCreatedMember(item.Id),
+	)
 	if item.Expires != nil {
-		args = append(args, item.Expires.Unix())
+		args = append(args, r.expireAt(*item.Expires))
 	}
 
 	result, err := redisClient.String(conn.Do("EVAL", args...))
 	if err != nil {
-		return errors.Wrap(err, "Error executing Lua script for check and set item")
+		return errors.Wrap(err, "Error executing Lua script for update item")
 	}
-	if result == errorDuplicate {
-		return model.ErrItemDuplicated
+	if result == errorNoLink {
+		return model.ErrNoLink
 	}
+	return nil
+}
 
+func (r *redis) Visit(decodedId uint64) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	result, err := redisClient.String(conn.Do("EVAL", visitScript, 1, getItemKey(decodedId)))
+	if err != nil {
+		return errors.Wrap(err, "Error executing Lua script for visit item")
+	}
+	if result == errorNoLink {
+		return model.ErrNoLink
+	}
 	return nil
 }
 
-func (r *redis) Find(url string) (uint64, error) {
-	return 0, fmt.Errorf("Not implemented")
+func (r *redis) Delete(decodedId uint64) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	return r.delete(conn, decodedId)
+}
+
+// delete removes item with its indexes and clicks
+func (r *redis) delete(conn redisClient.Conn, decodedId uint64) error {
+	old, err := r.get(conn, decodedId)
+	if err != nil {
+		return err
+	}
+
+	keys := []any{getItemKey(decodedId), getUrlKey(old.URL), createdKey, expiresKey}
+	if old.Alias != "" {
+		keys = append(keys, getAliasKey(old.Alias))
+	}
+	args := []any{deleteScript, len(keys)}
+	args = append(args, keys...)
+	args = append(args, decodedId, getCreatedMember(decodedId))
+
+	result, err := redisClient.String(conn.Do("EVAL", args...))
+	if err != nil {
+		return errors.Wrap(err, "Error executing Lua script for delete item")
+	}
+	if result == errorNoLink {
+		return model.ErrNoLink
+	}
+	return r.deleteClicks(conn, decodedId)
+}
+
+func (r *redis) deleteClicks(conn redisClient.Conn, decodedId uint64) error {
+	if _, err := conn.Do("DEL", getClicksKey(decodedId), getClickStatKey(decodedId)); err != nil {
+		return errors.Wrapf(err, "Can't delete clicks %v", decodedId)
+	}
+	return nil
+}
+
+// SaveClicks appends events to capped streams and increments counters in one pipeline
+func (r *redis) SaveClicks(clicks []model.Click) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	for _, c := range clicks {
+		if err := conn.Send("XADD", getClicksKey(c.LinkId), "MAXLEN", "~", clicksMaxLen, "*",
+			"time", c.Time.UnixMilli(), "referrer", c.Referrer, "userAgent", c.UserAgent,
+			"country", c.Country, "cacheHit", c.CacheHit,
+		); err != nil {
+			return errors.Wrap(err, "Can't send click")
+		}
+		statKey := getClickStatKey(c.LinkId)
+		if err := conn.Send("HINCRBY", statKey, "total", 1); err != nil {
+			return errors.Wrap(err, "Can't send click")
+		}
+		if err := conn.Send("HINCRBY", statKey, "day:"+c.Time.UTC().Format(model.ClickDateLayout), 1); err != nil {
+			return errors.Wrap(err, "Can't send click")
+		}
+		if c.CacheHit {
+			if err := conn.Send("HINCRBY", statKey, "cacheHits", 1); err != nil {
+				return errors.Wrap(err, "Can't send click")
+			}
+		}
+	}
+	_, err := conn.Do("")
+	return errors.Wrap(err, "Can't save clicks")
+}
+
+func (r *redis) ClickStat(decodedId uint64) (model.ClickStat, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	stat := model.ClickStat{Daily: []model.DailyClicks{}}
+	counters, err := redisClient.Int64Map(conn.Do("HGETALL", getClickStatKey(decodedId)))
+	if err != nil {
+		return stat, errors.Wrapf(err, "Can't get clicks %v", decodedId)
+	}
+	for name, count := range counters {
+		switch {
+		case name == "total":
+			stat.Total = count
+		case name == "cacheHits":
+			stat.CacheHits = count
+		case strings.HasPrefix(name, "day:"):
+			stat.Daily = append(stat.Daily, model.DailyClicks{Date: strings.TrimPrefix(name, "day:"), Count: count})
+		}
+	}
+	sort.Slice(stat.Daily, func(i, j int) bool {
+		return stat.Daily[i].Date < stat.Daily[j].Date
+	})
+	return stat, nil
+}
+
+// List reads created sorted set by pages, members of expired links are skipped
+func (r *redis) List(query model.ListQuery) ([]model.Item, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	command, from, to := "ZRANGEBYSCORE", "-inf", "+inf"
+	if query.Desc {
+		command, from, to = "ZREVRANGEBYSCORE", "+inf", "-inf"
+	}
+	var cursorScore int64
+	var cursorMember string
+	if query.Cursor != nil {
+		cursorScore = getCreatedScore(query.Cursor.Created)
+		cursorMember = getCreatedMember(query.Cursor.Id)
+		from = strconv.FormatInt(cursorScore, 10)
+	}
+
+	var items []model.Item
+	pageSize := query.Limit + 1
+	for offset := 0; len(items) < query.Limit; offset += pageSize {
+		values, err := redisClient.Values(conn.Do(command, createdKey, from, to, "WITHSCORES", "LIMIT", offset, pageSize))
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't read created index")
+		}
+		var page []struct {
+			Member string
+			Score  int64
+		}
+		if err := redisClient.ScanSlice(values, &page); err != nil {
+			return nil, errors.Wrap(err, "Can't scan created index")
+		}
+		for _, entry := range page {
+			if len(items) == query.Limit {
+				break
+			}
+			if query.Cursor != nil && entry.Score == cursorScore &&
+				(entry.Member <= cursorMember && !query.Desc || entry.Member >= cursorMember && query.Desc) {
+				continue
+			}
+			id, err := strconv.ParseUint(entry.Member, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Can't parse created member %v", entry.Member)
+			}
+			item, err := r.get(conn, id)
+			if errors.Is(err, model.ErrNoLink) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if query.Matches(item) {
+				items = append(items, item)
+			}
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+	return items, nil
+}
+
+func (r *redis) SaveToken(token model.Token) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	tokenRaw, err := json.Marshal(token)
+	if err != nil {
+		return errors.Wrap(err, "Can't marshal token")
+	}
+	created, err := redisClient.Bool(conn.Do("HSETNX", tokensKey, token.Name, tokenRaw))
+	if err != nil {
+		return errors.Wrap(err, "Can't save token")
+	}
+	if !created {
+		return model.ErrTokenDuplicated
+	}
+	return nil
+}
+
+func (r *redis) DeleteToken(name string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	deleted, err := redisClient.Bool(conn.Do("HDEL", tokensKey, name))
+	if err != nil {
+		return errors.Wrap(err, "Can't delete token")
+	}
+	if !deleted {
+		return model.ErrNoToken
+	}
+	return nil
+}
+
+func (r *redis) ListTokens() ([]model.Token, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redisClient.StringMap(conn.Do("HGETALL", tokensKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't list tokens")
+	}
+	tokens := make([]model.Token, 0, len(values))
+	for name, value := range values {
+		var token model.Token
+		if err := json.Unmarshal([]byte(value), &token); err != nil {
+			return nil, errors.Wrapf(err, "Can't unmarshal token %v", name)
+		}
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens, nil
 }
 
-func (r *redis) Load(decodedId uint64) (string, error) {
+// SaveCampaign creates or replaces campaign, creation time of replaced campaign is kept
+func (r *redis) SaveCampaign(campaign model.Campaign) error {
 	conn := r.pool.Get()
 	defer conn.Close()
 
-	urlString, err := redisClient.String(conn.Do("HGET", getItemKey(decodedId), "url"))
+	oldRaw, err := redisClient.Bytes(conn.Do("HGET", campaignsKey, campaign.Name))
+	if err != nil && !errors.Is(err, redisClient.ErrNil) {
+		return errors.Wrap(err, "Can't get campaign")
+	}
+	if err == nil {
+		var old model.Campaign
+		if err := json.Unmarshal(oldRaw, &old); err != nil {
+			return errors.Wrapf(err, "Can't unmarshal campaign %v", campaign.Name)
+		}
+		campaign.Created = old.Created
+	}
+	campaignRaw, err := json.Marshal(campaign)
 	if err != nil {
-		return "", err
-	} else if len(urlString) == 0 {
-		return "", model.ErrNoLink
+		return errors.Wrap(err, "Can't marshal campaign")
 	}
+	_, err = conn.Do("HSET", campaignsKey, campaign.Name, campaignRaw)
+	return errors.Wrap(err, "Can't save campaign")
+}
 
-	return urlString, nil
+func (r *redis) DeleteCampaign(name string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	deleted, err := redisClient.Bool(conn.Do("HDEL", campaignsKey, name))
+	if err != nil {
+		return errors.Wrap(err, "Can't delete campaign")
+	}
+	if !deleted {
+		return model.ErrNoCampaign
+	}
+	return nil
+}
+
+func (r *redis) ListCampaigns() ([]model.Campaign, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	values, err := redisClient.StringMap(conn.Do("HGETALL", campaignsKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't list campaigns")
+	}
+	campaigns := make([]model.Campaign, 0, len(values))
+	for name, value := range values {
+		var campaign model.Campaign
+		if err := json.Unmarshal([]byte(value), &campaign); err != nil {
+			return nil, errors.Wrapf(err, "Can't unmarshal campaign %v", name)
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	sort.Slice(campaigns, func(i, j int) bool {
+		return campaigns[i].Name < campaigns[j].Name
+	})
+	return campaigns, nil
+}
+
+func (r *redis) NextSequence() (uint64, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	value, err := redisClient.Uint64(conn.Do("INCR", sequenceKey))
+	return value, errors.Wrap(err, "Can't increment sequence")
 }
 
 func (r *redis) Close() error {
 	return r.pool.Close()
 }
 
+func (r *redis) ping(conn redisClient.Conn) (time.Duration, error) {
+	t := time.Now()
+	if _, err := conn.Do("PING"); err != nil {
+		return 0, errors.Wrap(err, "Can't ping")
+	}
+	return time.Since(t), nil
+}
+
+// countKeys counts keys by pattern with SCAN, because KEYS blocks the server
+func (r *redis) countKeys(conn redisClient.Conn, pattern string) (int, error) {
+	count := 0
+	cursor := 0
+	for {
+		values, err := redisClient.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 1000))
+		if err != nil {
+			return 0, errors.Wrapf(err, "Can't scan keys %v", pattern)
+		}
+		var keys []string
+		if _, err := redisClient.Scan(values, &cursor, &keys); err != nil {
+			return 0, errors.Wrapf(err, "Can't parse scan result %v", pattern)
+		}
+		count += len(keys)
+		if cursor == 0 {
+			return count, nil
+		}
+	}
+}
+
+// memoryInfo returns human readable values of INFO memory section
+func (r *redis) memoryInfo(conn redisClient.Conn) (map[string]string, error) {
+	info, err := redisClient.String(conn.Do("INFO", "memory"))
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't get memory info")
+	}
+	ret := make(map[string]string)
+	for _, line := range strings.Split(info, "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.HasSuffix(name, "_human") {
+			ret[strings.TrimSuffix(name, "_human")] = value
+		}
+	}
+	return ret, nil
+}
+
 func (r *redis) Stat(ctx context.Context) (any, error) {
-	return "Not implemented", nil
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't get redis connection")
+	}
+	defer conn.Close()
+
+	pingDuration, err := r.ping(conn)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't ping redis")
+	}
+	linkCount, err := r.countKeys(conn, "link:*")
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't count links")
+	}
+	memory, err := r.memoryInfo(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	s := r.pool.Stats()
+	return struct {
+		PingDuration time.Duration     `json:"pingDuration"`
+		ActiveCount  int               `json:"activeCount"`
+		IdleCount    int               `json:"idleCount"`
+		WaitCount    int64             `json:"waitCount"`
+		WaitDuration time.Duration     `json:"waitDuration"`
+		LinkCount    int               `json:"linkCount"`
+		Memory       map[string]string `json:"memory"`
+	}{
+		PingDuration: pingDuration,
+		ActiveCount:  s.ActiveCount,
+		IdleCount:    s.IdleCount,
+		WaitCount:    s.WaitCount,
+		WaitDuration: s.WaitDuration,
+		LinkCount:    linkCount,
+		Memory:       memory,
+	}, nil
 }
@@ -0,0 +1,23 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CleanExpired deletes items expired before the time
+func (s *Sqlite) CleanExpired(before time.Time) error {
+	expires := before.Unix()
+	err := s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(s.ctx,
+			"DELETE FROM clicks WHERE link_id IN (SELECT id FROM links WHERE expires IS NOT NULL AND expires < $1)", expires,
+		); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(s.ctx, "DELETE FROM links WHERE expires IS NOT NULL AND expires < $1", expires)
+		return err
+	})
+	return errors.Wrap(err, "Can't delete expires links")
+}
@@ -0,0 +1,556 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func migrate(ctx context.Context, db *sql.DB) error {
+
+	if err := migrationV1(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV2(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV3(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV4(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV5(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV6(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV7(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV8(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV9(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV10(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV11(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV12(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV13(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV14(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV15(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV16(ctx, db); err != nil {
+		return err
+	}
+	if err := migrationV17(ctx, db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func exists(ctx context.Context, db *sql.DB, kind, name string) (bool, error) {
+	var ret bool
+	err := db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = $1 AND name = $2)", kind, name,
+	).Scan(&ret)
+	return ret, err
+}
+
+func columnExists(ctx context.Context, db *sql.DB, table, column string) (bool, error) {
+	var ret bool
+	err := db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pragma_table_info($1) WHERE name = $2)", table, column,
+	).Scan(&ret)
+	return ret, err
+}
+
+func migrationV1(ctx context.Context, db *sql.DB) error {
+	tableExists, err := exists(ctx, db, "table", "links")
+	if err != nil {
+		return err
+	}
+
+	if tableExists {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V1...")
+
+	// expires is stored as unix seconds, so it is compared as a number
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE links (
+			id INTEGER NOT NULL,
+			url TEXT NOT NULL,
+			expires INTEGER
+		)
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE UNIQUE INDEX links_id_uniq ON links (id)
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE INDEX links_expires_idx ON links (expires) WHERE expires IS NOT NULL
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV2(ctx context.Context, db *sql.DB) error {
+	indexExists, err := exists(ctx, db, "index", "links_url_idx")
+	if err != nil {
+		return err
+	}
+
+	if indexExists {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V2...")
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE INDEX links_url_idx ON links (url)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV3(ctx context.Context, db *sql.DB) error {
+	indexExists, err := exists(ctx, db, "index", "links_alias_uniq")
+	if err != nil {
+		return err
+	}
+
+	if indexExists {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V3...")
+
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN alias TEXT
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE UNIQUE INDEX links_alias_uniq ON links (alias) WHERE alias IS NOT NULL
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV4(ctx context.Context, db *sql.DB) error {
+	indexExists, err := exists(ctx, db, "index", "links_created_idx")
+	if err != nil {
+		return err
+	}
+
+	if indexExists {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V4...")
+
+	// created is stored as unix nanoseconds, links created before have 0
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN created INTEGER NOT NULL DEFAULT 0
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE INDEX links_created_idx ON links (created, id)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV5(ctx context.Context, db *sql.DB) error {
+	tableExists, err := exists(ctx, db, "table", "clicks")
+	if err != nil {
+		return err
+	}
+
+	if tableExists {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V5...")
+
+	// time is stored as unix seconds
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE clicks (
+			link_id INTEGER NOT NULL,
+			time INTEGER NOT NULL,
+			referrer TEXT NOT NULL,
+			user_agent TEXT NOT NULL,
+			country TEXT NOT NULL,
+			cache_hit INTEGER NOT NULL
+		)
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE INDEX clicks_link_id_time_idx ON clicks (link_id, time)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV6(ctx context.Context, db *sql.DB) error {
+	tableExists, err := exists(ctx, db, "table", "sequences")
+	if err != nil {
+		return err
+	}
+
+	if tableExists {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V6...")
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE sequences (
+			name TEXT PRIMARY KEY,
+			value INTEGER NOT NULL
+		)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV7(ctx context.Context, db *sql.DB) error {
+	indexExists, err := exists(ctx, db, "index", "links_owner_created_idx")
+	if err != nil {
+		return err
+	}
+
+	if indexExists {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V7...")
+
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN owner TEXT NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE INDEX links_owner_created_idx ON links (owner, created, id)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV8(ctx context.Context, db *sql.DB) error {
+	tableExists, err := exists(ctx, db, "table", "tokens")
+	if err != nil {
+		return err
+	}
+
+	if tableExists {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V8...")
+
+	// scopes are joined by comma, created is stored as unix nanoseconds
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE tokens (
+			name TEXT PRIMARY KEY,
+			hash TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			created INTEGER NOT NULL
+		)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV9(ctx context.Context, db *sql.DB) error {
+	hasColumn, err := columnExists(ctx, db, "links", "password_hash")
+	if err != nil {
+		return err
+	}
+
+	if hasColumn {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V9...")
+
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV10(ctx context.Context, db *sql.DB) error {
+	hasColumn, err := columnExists(ctx, db, "links", "visits")
+	if err != nil {
+		return err
+	}
+
+	if hasColumn {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V10...")
+
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN max_visits INTEGER NOT NULL DEFAULT 0
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN visits INTEGER NOT NULL DEFAULT 0
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV11(ctx context.Context, db *sql.DB) error {
+	hasColumn, err := columnExists(ctx, db, "links", "active_from")
+	if err != nil {
+		return err
+	}
+
+	if hasColumn {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V11...")
+
+	// active_from is stored as unix seconds like expires
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN active_from INTEGER
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV12(ctx context.Context, db *sql.DB) error {
+	hasColumn, err := columnExists(ctx, db, "links", "expired_url")
+	if err != nil {
+		return err
+	}
+
+	if hasColumn {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V12...")
+
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN expired_url TEXT NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV13(ctx context.Context, db *sql.DB) error {
+	hasColumn, err := columnExists(ctx, db, "links", "redirect_type")
+	if err != nil {
+		return err
+	}
+
+	if hasColumn {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V13...")
+
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN redirect_type INTEGER NOT NULL DEFAULT 0
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV14(ctx context.Context, db *sql.DB) error {
+	hasColumn, err := columnExists(ctx, db, "links", "query_allow")
+	if err != nil {
+		return err
+	}
+
+	if hasColumn {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V14...")
+
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN query_policy TEXT NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN query_allow TEXT NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV15(ctx context.Context, db *sql.DB) error {
+	tableExists, err := exists(ctx, db, "table", "campaigns")
+	if err != nil {
+		return err
+	}
+
+	if tableExists {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V15...")
+
+	// params are stored as json object, created is stored as unix nanoseconds
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE campaigns (
+			name TEXT PRIMARY KEY,
+			params TEXT NOT NULL,
+			created INTEGER NOT NULL
+		)
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV16(ctx context.Context, db *sql.DB) error {
+	hasColumn, err := columnExists(ctx, db, "links", "campaign")
+	if err != nil {
+		return err
+	}
+
+	if hasColumn {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V16...")
+
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN campaign TEXT NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		CREATE INDEX links_campaign_idx ON links (campaign, created, id) WHERE campaign != ''
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
+
+func migrationV17(ctx context.Context, db *sql.DB) error {
+	hasColumn, err := columnExists(ctx, db, "links", "device_rules")
+	if err != nil {
+		return err
+	}
+
+	if hasColumn {
+		return nil
+	}
+
+	log.Infoln("Sqlite migrates V17...")
+
+	// device rules are stored as json array
+	if _, err := db.ExecContext(ctx, `
+		ALTER TABLE links ADD COLUMN device_rules TEXT NOT NULL DEFAULT ''
+	`); err != nil {
+		return err
+	}
+
+	log.Infoln("Migrate finished")
+
+	return nil
+}
@@ -0,0 +1,544 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	sqliteDriver "modernc.org/sqlite"
+	sqliteLib "modernc.org/sqlite/lib"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+// defaultTimeout is time to wait for locked database, when timeout isn't configured
+const defaultTimeout = 5 * time.Second
+
+type Sqlite struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+func New(ctx context.Context, path string, timeout time.Duration) (*Sqlite, error) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	params := url.Values{}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", timeout.Milliseconds()))
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to open database")
+	}
+	// sqlite has single writer, so the only connection serializes writes instead of failing them
+	// with busy error, when transaction can't upgrade its lock
+	db.SetMaxOpenConns(1)
+
+	if err = migrate(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "Unable to roll migrations to database")
+	}
+
+	return &Sqlite{ctx: ctx, db: db}, nil
+}
+
+func (s *Sqlite) exec(query string, args ...interface{}) error {
+	_, err := s.db.ExecContext(s.ctx, query, args...)
+	return err
+}
+
+// inTx executes fn in transaction, which is committed if fn succeeds
+func (s *Sqlite) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "Can't begin transaction")
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "Can't commit transaction")
+}
+
+// unixOrNil converts expires to unix seconds, which are stored in expires column
+func unixOrNil(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	ret := t.Unix()
+	return &ret
+}
+
+// nullIfEmpty converts empty string to NULL, so unique index ignores it
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// unixNano converts created to unix nanoseconds, zero time is stored as 0
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+const insertItem = "INSERT INTO links (id, url, alias, expires, created, owner, password_hash, max_visits, active_from, expired_url, " +
+	"redirect_type, query_policy, query_allow, campaign, device_rules) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)"
+
+func itemArgs(item model.Item) []any {
+	return []any{
+		int64(item.Id), item.URL, nullIfEmpty(item.Alias), unixOrNil(item.Expires), unixNano(item.Created),
+		item.Owner, item.PasswordHash, item.MaxVisits, unixOrNil(item.ActiveFrom), item.ExpiredURL, item.RedirectType,
+		item.QueryPolicy, strings.Join(item.QueryAllow, ","), item.Campaign, model.EncodeDeviceRules(item.DeviceRules),
+	}
+}
+
+// createError converts unique constraint error of insert to model.ErrItemDuplicated or model.ErrAliasDuplicated
+func createError(err error) error {
+	var sqliteErr *sqliteDriver.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqliteLib.SQLITE_CONSTRAINT_UNIQUE {
+		// sqlite reports constraint only in message: UNIQUE constraint failed: links.alias
+		if strings.Contains(sqliteErr.Error(), "links.alias") {
+			return model.ErrAliasDuplicated
+		}
+		return model.ErrItemDuplicated
+	}
+	return err
+}
+
+func (s *Sqlite) Create(item model.Item) error {
+	return createError(s.exec(insertItem, itemArgs(item)...))
+}
+
+// CreateBatch inserts items in single transaction, failed statement of duplicated item doesn't roll back others
+func (s *Sqlite) CreateBatch(items []model.Item) ([]error, error) {
+	errs := make([]error, len(items))
+	err := s.inTx(func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(s.ctx, insertItem)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for i, item := range items {
+			_, err := stmt.ExecContext(s.ctx, itemArgs(item)...)
+			if err = createError(err); err != nil {
+				if !errors.Is(err, model.ErrItemDuplicated) {
+					return err
+				}
+				errs[i] = err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't insert items")
+	}
+	return errs, nil
+}
+
+func (s *Sqlite) Find(url string) (uint64, error) {
+	row := s.db.QueryRowContext(s.ctx,
+		"SELECT id FROM links WHERE url = $1 AND (expires IS NULL OR expires >= $2) AND password_hash = '' AND max_visits = 0",
+		url, time.Now().Unix(),
+	)
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, errors.Wrapf(err, "Can't scan id %v", url)
+	}
+	return uint64(id), nil
+}
+
+func (s *Sqlite) Load(decodedId uint64) (model.Item, error) {
+	row := s.db.QueryRowContext(s.ctx, "SELECT "+itemColumns+" FROM links WHERE id = $1", int64(decodedId))
+	item, err := scanActiveItem(row)
+	if err != nil && !errors.Is(err, model.ErrNoLink) {
+		return model.Item{}, errors.Wrapf(err, "Can't scan item %v", int64(decodedId))
+	}
+	return item, err
+}
+
+func (s *Sqlite) LoadByAlias(alias string) (model.Item, error) {
+	row := s.db.QueryRowContext(s.ctx, "SELECT "+itemColumns+" FROM links WHERE alias = $1", alias)
+	item, err := scanActiveItem(row)
+	if err != nil && !errors.Is(err, model.ErrNoLink) {
+		return model.Item{}, errors.Wrapf(err, "Can't scan item %v", alias)
+	}
+	return item, err
+}
+
+const itemColumns = "id, url, COALESCE(alias, ''), expires, created, owner, password_hash, max_visits, visits, active_from, expired_url, " +
+	"redirect_type, query_policy, query_allow, campaign, device_rules"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanItem(row scanner) (model.Item, error) {
+	var item model.Item
+	var id, created int64
+	var expires, activeFrom *int64
+	var queryAllow, deviceRules string
+	if err := row.Scan(&id, &item.URL, &item.Alias, &expires, &created, &item.Owner, &item.PasswordHash,
+		&item.MaxVisits, &item.Visits, &activeFrom, &item.ExpiredURL,
+		&item.RedirectType, &item.QueryPolicy, &queryAllow, &item.Campaign, &deviceRules,
+	); err != nil {
+		return model.Item{}, err
+	}
+	item.Id = uint64(id)
+	if queryAllow != "" {
+		item.QueryAllow = strings.Split(queryAllow, ",")
+	}
+	rules, err := model.DecodeDeviceRules(deviceRules)
+	if err != nil {
+		return model.Item{}, errors.Wrapf(err, "Can't decode device rules of item %v", item.Id)
+	}
+	item.DeviceRules = rules
+	if expires != nil {
+		t := time.Unix(*expires, 0)
+		item.Expires = &t
+	}
+	if activeFrom != nil {
+		t := time.Unix(*activeFrom, 0)
+		item.ActiveFrom = &t
+	}
+	if created != 0 {
+		item.Created = time.Unix(0, created)
+	}
+	return item, nil
+}
+
+// scanActiveItem returns item from row, model.ErrNoLink if row is absent, *model.ExpiredError if it is expired
+// or has no visits left
+func scanActiveItem(row scanner) (model.Item, error) {
+	item, err := scanItem(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Item{}, model.ErrNoLink
+		}
+		return model.Item{}, err
+	}
+	if err := item.CheckActive(time.Now()); err != nil {
+		return model.Item{}, err
+	}
+	return item, nil
+}
+
+func (s *Sqlite) Get(decodedId uint64) (model.Item, error) {
+	row := s.db.QueryRowContext(s.ctx, "SELECT "+itemColumns+" FROM links WHERE id = $1", int64(decodedId))
+	item, err := scanItem(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Item{}, model.ErrNoLink
+		}
+		return model.Item{}, errors.Wrapf(err, "Can't scan item %v", int64(decodedId))
+	}
+	return item, nil
+}
+
+func (s *Sqlite) FindAlias(alias string) (uint64, error) {
+	row := s.db.QueryRowContext(s.ctx, "SELECT id FROM links WHERE alias = $1", alias)
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, errors.Wrapf(err, "Can't scan id %v", alias)
+	}
+	return uint64(id), nil
+}
+
+// execAffected executes query and returns model.ErrNoLink if no row was affected
+func (s *Sqlite) execAffected(query string, args ...interface{}) error {
+	result, err := s.db.ExecContext(s.ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return model.ErrNoLink
+	}
+	return nil
+}
+
+func (s *Sqlite) Update(item model.Item) error {
+	return s.execAffected(
+		"UPDATE links SET url = $2, expires = $3, active_from = $4, expired_url = $5, redirect_type = $6, "+
+			"query_policy = $7, query_allow = $8, campaign = $9, device_rules = $10 WHERE id = $1",
+		int64(item.Id), item.URL, unixOrNil(item.Expires), unixOrNil(item.ActiveFrom), item.ExpiredURL, item.RedirectType,
+		item.QueryPolicy, strings.Join(item.QueryAllow, ","), item.Campaign, model.EncodeDeviceRules(item.DeviceRules),
+	)
+}
+
+// Visit counts visit of link with visit limit by single statement, so concurrent visits can't exceed the limit
+func (s *Sqlite) Visit(decodedId uint64) error {
+	return s.execAffected(`
+		UPDATE links SET visits = visits + CASE WHEN max_visits > 0 THEN 1 ELSE 0 END
+		WHERE id = $1 AND (expires IS NULL OR expires >= $2) AND (max_visits = 0 OR visits < max_visits)`,
+		int64(decodedId), time.Now().Unix(),
+	)
+}
+
+func (s *Sqlite) Delete(decodedId uint64) error {
+	if err := s.execAffected("DELETE FROM links WHERE id = $1", int64(decodedId)); err != nil {
+		return err
+	}
+	return errors.Wrap(s.exec("DELETE FROM clicks WHERE link_id = $1", int64(decodedId)), "Can't delete clicks")
+}
+
+func (s *Sqlite) List(query model.ListQuery) ([]model.Item, error) {
+	op, order := ">", "ASC"
+	if query.Desc {
+		op, order = "<", "DESC"
+	}
+	sqlQuery := "SELECT " + itemColumns + " FROM links"
+	var conditions []string
+	var args []interface{}
+	if query.Cursor != nil {
+		args = append(args, unixNano(query.Cursor.Created), int64(query.Cursor.Id))
+		conditions = append(conditions, fmt.Sprintf("(created, id) %s ($%d, $%d)", op, len(args)-1, len(args)))
+	}
+	if query.Owner != "" {
+		args = append(args, query.Owner)
+		conditions = append(conditions, fmt.Sprintf("owner = $%d", len(args)))
+	}
+	if query.Campaign != "" {
+		args = append(args, query.Campaign)
+		conditions = append(conditions, fmt.Sprintf("campaign = $%d", len(args)))
+	}
+	if len(conditions) > 0 {
+		sqlQuery += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	sqlQuery += fmt.Sprintf(" ORDER BY created %[1]s, id %[1]s LIMIT %[2]d", order, query.Limit)
+
+	rows, err := s.db.QueryContext(s.ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't query items")
+	}
+	defer rows.Close()
+
+	var items []model.Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't scan item")
+		}
+		items = append(items, item)
+	}
+	return items, errors.Wrap(rows.Err(), "Can't read items")
+}
+
+func (s *Sqlite) SaveClicks(clicks []model.Click) error {
+	err := s.inTx(func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(s.ctx,
+			"INSERT INTO clicks (link_id, time, referrer, user_agent, country, cache_hit) VALUES ($1, $2, $3, $4, $5, $6)",
+		)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, c := range clicks {
+			if _, err := stmt.ExecContext(s.ctx,
+				int64(c.LinkId), c.Time.Unix(), c.Referrer, c.UserAgent, c.Country, c.CacheHit,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "Can't insert clicks")
+}
+
+func (s *Sqlite) ClickStat(decodedId uint64) (model.ClickStat, error) {
+	stat := model.ClickStat{Daily: []model.DailyClicks{}}
+	rows, err := s.db.QueryContext(s.ctx, `
+		SELECT strftime('%Y-%m-%d', time, 'unixepoch') AS day, count(*), sum(cache_hit)
+		FROM clicks WHERE link_id = $1 GROUP BY day ORDER BY day
+	`, int64(decodedId))
+	if err != nil {
+		return stat, errors.Wrap(err, "Can't query clicks")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day model.DailyClicks
+		var cacheHits int64
+		if err := rows.Scan(&day.Date, &day.Count, &cacheHits); err != nil {
+			return stat, errors.Wrap(err, "Can't scan clicks")
+		}
+		stat.Total += day.Count
+		stat.CacheHits += cacheHits
+		stat.Daily = append(stat.Daily, day)
+	}
+	return stat, errors.Wrap(rows.Err(), "Can't read clicks")
+}
+
+func (s *Sqlite) SaveToken(token model.Token) error {
+	err := s.exec(
+		"INSERT INTO tokens (name, hash, scopes, created) VALUES ($1, $2, $3, $4)",
+		token.Name, token.Hash, strings.Join(token.Scopes, ","), unixNano(token.Created),
+	)
+	if err != nil {
+		var sqliteErr *sqliteDriver.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqliteLib.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return model.ErrTokenDuplicated
+		}
+		return errors.Wrap(err, "Can't insert token")
+	}
+	return nil
+}
+
+func (s *Sqlite) DeleteToken(name string) error {
+	res, err := s.db.ExecContext(s.ctx, "DELETE FROM tokens WHERE name = $1", name)
+	if err != nil {
+		return errors.Wrap(err, "Can't delete token")
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "Can't get affected rows")
+	}
+	if affected == 0 {
+		return model.ErrNoToken
+	}
+	return nil
+}
+
+func (s *Sqlite) ListTokens() ([]model.Token, error) {
+	rows, err := s.db.QueryContext(s.ctx, "SELECT name, hash, scopes, created FROM tokens ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't query tokens")
+	}
+	defer rows.Close()
+
+	tokens := make([]model.Token, 0)
+	for rows.Next() {
+		var token model.Token
+		var scopes string
+		var created int64
+		if err := rows.Scan(&token.Name, &token.Hash, &scopes, &created); err != nil {
+			return nil, errors.Wrap(err, "Can't scan token")
+		}
+		if scopes != "" {
+			token.Scopes = strings.Split(scopes, ",")
+		}
+		token.Created = time.Unix(0, created)
+		tokens = append(tokens, token)
+	}
+	return tokens, errors.Wrap(rows.Err(), "Can't read tokens")
+}
+
+// SaveCampaign creates or replaces campaign, creation time of replaced campaign is kept
+func (s *Sqlite) SaveCampaign(campaign model.Campaign) error {
+	params, err := json.Marshal(campaign.Params)
+	if err != nil {
+		return errors.Wrap(err, "Can't marshal campaign params")
+	}
+	err = s.exec(
+		"INSERT INTO campaigns (name, params, created) VALUES ($1, $2, $3) "+
+			"ON CONFLICT (name) DO UPDATE SET params = excluded.params",
+		campaign.Name, string(params), unixNano(campaign.Created),
+	)
+	return errors.Wrap(err, "Can't save campaign")
+}
+
+func (s *Sqlite) DeleteCampaign(name string) error {
+	err := s.execAffected("DELETE FROM campaigns WHERE name = $1", name)
+	if errors.Is(err, model.ErrNoLink) {
+		return model.ErrNoCampaign
+	}
+	return errors.Wrap(err, "Can't delete campaign")
+}
+
+func (s *Sqlite) ListCampaigns() ([]model.Campaign, error) {
+	rows, err := s.db.QueryContext(s.ctx, "SELECT name, params, created FROM campaigns ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't query campaigns")
+	}
+	defer rows.Close()
+
+	campaigns := make([]model.Campaign, 0)
+	for rows.Next() {
+		var campaign model.Campaign
+		var params string
+		var created int64
+		if err := rows.Scan(&campaign.Name, &params, &created); err != nil {
+			return nil, errors.Wrap(err, "Can't scan campaign")
+		}
+		if err := json.Unmarshal([]byte(params), &campaign.Params); err != nil {
+			return nil, errors.Wrapf(err, "Can't unmarshal params of campaign %v", campaign.Name)
+		}
+		campaign.Created = time.Unix(0, created)
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, errors.Wrap(rows.Err(), "Can't read campaigns")
+}
+
+func (s *Sqlite) NextSequence() (uint64, error) {
+	row := s.db.QueryRowContext(s.ctx, `
+		INSERT INTO sequences (name, value) VALUES ('links', 1)
+		ON CONFLICT (name) DO UPDATE SET value = value + 1
+		RETURNING value
+	`)
+	var value int64
+	if err := row.Scan(&value); err != nil {
+		return 0, errors.Wrap(err, "Can't get next value of sequence")
+	}
+	return uint64(value), nil
+}
+
+func (s *Sqlite) Close() error {
+	return s.db.Close()
+}
+
+func (s *Sqlite) ping() (time.Duration, error) {
+	t := time.Now()
+	var n int
+	if err := s.db.QueryRowContext(s.ctx, "SELECT 1").Scan(&n); err != nil {
+		return 0, errors.Wrapf(err, "Can't scan on ping")
+	}
+	return time.Since(t), nil
+}
+
+func (s *Sqlite) Stat(ctx context.Context) (any, error) {
+	pingDuration, err := s.ping()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't ping sqlite")
+	}
+
+	var linkCount int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM links").Scan(&linkCount); err != nil {
+		return nil, errors.Wrapf(err, "Can't count links")
+	}
+
+	st := s.db.Stats()
+	return struct {
+		PingDuration    time.Duration `json:"pingDuration"`
+		LinkCount       int64         `json:"linkCount"`
+		OpenConnections int           `json:"openConnections"`
+		InUse           int           `json:"inUse"`
+		Idle            int           `json:"idle"`
+		WaitCount       int64         `json:"waitCount"`
+		WaitDuration    time.Duration `json:"waitDuration"`
+	}{
+		PingDuration:    pingDuration,
+		LinkCount:       linkCount,
+		OpenConnections: st.OpenConnections,
+		InUse:           st.InUse,
+		Idle:            st.Idle,
+		WaitCount:       st.WaitCount,
+		WaitDuration:    st.WaitDuration,
+	}, nil
+}
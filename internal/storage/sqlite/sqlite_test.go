@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+func TestSqlite_ConcurrentWrites(t *testing.T) {
+	// zero timeout uses default one
+	s, err := New(context.Background(), filepath.Join(t.TempDir(), "sqlite.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 50)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := uint64(i + 1)
+			if i%2 == 0 {
+				errs[i] = s.Create(model.Item{Id: id, URL: fmt.Sprintf("http://example.com/%v", id), Created: time.Now()})
+				return
+			}
+			errs[i] = s.SaveClicks([]model.Click{{LinkId: id, Time: time.Now()}})
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+}
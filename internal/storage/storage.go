@@ -2,7 +2,6 @@ package storage
 
 import (
 	"context"
-	"math/rand"
 	"time"
 
 	"github.com/pkg/errors"
@@ -10,44 +9,92 @@ import (
 
 	"github.com/sergiusd/go-scanty-url-shortener/internal/base62"
 	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/metrics"
 	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
 	"github.com/sergiusd/go-scanty-url-shortener/internal/storage/bolt"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/storage/memory"
 	"github.com/sergiusd/go-scanty-url-shortener/internal/storage/psql"
 	"github.com/sergiusd/go-scanty-url-shortener/internal/storage/redis"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/storage/sqlite"
 )
 
+// maxCollisions is count of drawn ids, which are taken, after that saving fails
+const maxCollisions = 1000
+
+// campaignStatPage is count of links loaded at once for campaign statistics
+const campaignStatPage = 500
+
 type Storage struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 	client client
+	codec  *base62.Codec
+	idGen  IDGenerator
+	// mayBeAlias reports whether generated code may be taken by alias, nil means any code
+	mayBeAlias func(code string) bool
 }
 
 type client interface {
 	Create(item model.Item) error
+	// CreateBatch creates items at once and returns error of each item, model.ErrItemDuplicated and
+	// model.ErrAliasDuplicated don't prevent creation of other items
+	CreateBatch(items []model.Item) ([]error, error)
+	// Find returns id of reusable item by url, zero if there is no such item
 	Find(url string) (uint64, error)
-	Load(decodedId uint64) (string, error)
+	Load(decodedId uint64) (model.Item, error)
+	LoadByAlias(alias string) (model.Item, error)
+	Get(decodedId uint64) (model.Item, error)
+	FindAlias(alias string) (uint64, error)
+	Update(item model.Item) error
+	Visit(decodedId uint64) error
+	Delete(decodedId uint64) error
+	List(query model.ListQuery) ([]model.Item, error)
+	SaveClicks(clicks []model.Click) error
+	ClickStat(decodedId uint64) (model.ClickStat, error)
+	NextSequence() (uint64, error)
+	SaveToken(token model.Token) error
+	DeleteToken(name string) error
+	ListTokens() ([]model.Token, error)
+	// SaveCampaign creates or replaces campaign, creation time of replaced campaign is kept
+	SaveCampaign(campaign model.Campaign) error
+	DeleteCampaign(name string) error
+	ListCampaigns() ([]model.Campaign, error)
 	Close() error
 	Stat(ctx context.Context) (interface{}, error)
 }
 
 type clientCleaner interface {
-	CleanExpired() error
+	// CleanExpired deletes items expired before the time, items without visits left are kept until they expire
+	CleanExpired(before time.Time) error
+}
+
+// cleanableClient is implemented by every client, so a client without cleaner breaks the build
+// instead of keeping expired items forever
+type cleanableClient interface {
+	client
+	clientCleaner
 }
 
-func New(conf config.Storage) (*Storage, error) {
+func New(conf config.Storage, codec *base62.Codec) (*Storage, error) {
 	var err error
-	var client client
+	var client cleanableClient
 	ctx, cancel := context.WithCancel(context.Background())
 	switch conf.Kind {
 	case "redis":
 		log.Infof("Use redis on %v:%v", conf.Redis.Host, conf.Redis.Port)
-		client, err = redis.New(conf.Redis.Host, conf.Redis.Port, conf.Redis.Password)
+		client, err = redis.New(conf.Redis.Host, conf.Redis.Port, conf.Redis.Password, conf.ExpiredGrace.Duration)
 	case "psql":
 		log.Infof("Use postgres on %v@%v:%v/%v, pool %v, timeout %v", conf.Psql.User, conf.Psql.Host, conf.Psql.Port, conf.Psql.Name, conf.Psql.PoolSize, conf.Psql.Timeout.Duration)
 		client, err = psql.New(ctx, conf.Psql.Host, conf.Psql.Port, conf.Psql.Name, conf.Psql.User, conf.Psql.Password, conf.Psql.PoolSize, conf.Psql.Timeout.Duration)
 	case "bolt":
 		log.Infof("Use bolt on %v:%v, timeout %v", conf.Bolt.Path, conf.Bolt.Bucket, conf.Psql.Timeout.Duration)
 		client, err = bolt.New(conf.Bolt.Path, conf.Bolt.Bucket, conf.Psql.Timeout.Duration)
+	case "sqlite":
+		log.Infof("Use sqlite on %v, timeout %v", conf.Sqlite.Path, conf.Sqlite.Timeout.Duration)
+		client, err = sqlite.New(ctx, conf.Sqlite.Path, conf.Sqlite.Timeout.Duration)
+	case "memory":
+		log.Infof("Use memory, snapshot %v", conf.Memory.Path)
+		client, err = memory.New(conf.Memory.Path)
 
 	default:
 		cancel()
@@ -58,38 +105,80 @@ func New(conf config.Storage) (*Storage, error) {
 		return nil, errors.Wrap(err, "Can't initialize storage")
 	}
 
-	if cleaner, ok := client.(clientCleaner); ok {
-		go startCleanScheduler(ctx, cleaner)
-	}
+	go startCleanScheduler(ctx, client, conf.ExpiredGrace.Duration)
 
-	return &Storage{client: client, ctx: ctx, cancel: cancel}, nil
+	return &Storage{client: client, ctx: ctx, cancel: cancel, codec: codec, idGen: NewRandomGenerator(codec.IDRange())}, nil
 }
 
-var r = rand.New(rand.NewSource(time.Now().Unix()))
+// NextSequence returns next value of storage sequence, starting from 1
+func (s *Storage) NextSequence() (uint64, error) {
+	return s.client.NextSequence()
+}
 
-func (s *Storage) Save(url string, expires *time.Time, tryFindExists bool) (string, error) {
-	if tryFindExists {
-		id, err := s.client.Find(url)
-		if err != nil {
-			return "", errors.Wrap(err, "Can't storage try find exists")
-		}
-		if id != 0 {
-			return base62.Encode(id), nil
-		}
+// SaveToken stores new token, model.ErrTokenDuplicated is returned if name is taken
+func (s *Storage) SaveToken(token model.Token) error {
+	return s.client.SaveToken(token)
+}
+
+func (s *Storage) DeleteToken(name string) error {
+	return s.client.DeleteToken(name)
+}
+
+func (s *Storage) ListTokens() ([]model.Token, error) {
+	return s.client.ListTokens()
+}
+
+// SaveCampaign creates or replaces campaign
+func (s *Storage) SaveCampaign(campaign model.Campaign) error {
+	return s.client.SaveCampaign(campaign)
+}
+
+// DeleteCampaign deletes campaign, model.ErrNoCampaign is returned if it doesn't exist
+func (s *Storage) DeleteCampaign(name string) error {
+	return s.client.DeleteCampaign(name)
+}
+
+func (s *Storage) ListCampaigns() ([]model.Campaign, error) {
+	return s.client.ListCampaigns()
+}
+
+// SetIDGenerator replaces generator of ids, it must be called before the first Save
+func (s *Storage) SetIDGenerator(idGen IDGenerator) {
+	s.idGen = idGen
+}
+
+// SetAliasMatcher sets check of codes, which may be taken by alias, generated codes not passing it
+// aren't looked up among aliases, it must be called before the first Save
+func (s *Storage) SetAliasMatcher(mayBeAlias func(code string) bool) {
+	s.mayBeAlias = mayBeAlias
+}
+
+// Save stores item with a new unique id and returns its short code, which is the alias if it is set
+func (s *Storage) Save(item model.Item, tryFindExists bool) (string, error) {
+	code, err := s.findExists(item, tryFindExists)
+	if err != nil || code != "" {
+		return code, err
 	}
 
-	item := model.Item{URL: url, Expires: expires}
+	item.Created = time.Now()
 	collisionCount := 0
 
 	for {
-		if collisionCount > 1000 {
+		if collisionCount > maxCollisions {
 			return "", errors.New("Collission happened more than 1000 times")
 		}
-		item.Id = r.Uint64()
-		err := s.client.Create(item)
+		id, err := s.nextID(&collisionCount)
+		if err != nil {
+			return "", err
+		}
+		item.Id = id
+		err = s.client.Create(item)
 		if err == nil {
 			break
 		}
+		if errors.Is(err, model.ErrAliasDuplicated) {
+			return "", err
+		}
 		if errors.Is(err, model.ErrItemDuplicated) {
 			collisionCount += 1
 			continue
@@ -98,16 +187,227 @@ func (s *Storage) Save(url string, expires *time.Time, tryFindExists bool) (stri
 	}
 
 	if collisionCount != 0 {
+		metrics.IdCollisionCounter.Add(float64(collisionCount))
 		log.Warnf("Collision on save unique short URL name: %v times", collisionCount)
 	}
 
-	return base62.Encode(item.Id), nil
+	return s.getCode(item), nil
 }
 
-func (s *Storage) Load(id uint64) (string, error) {
+// SaveBatch stores items like Save, but creates them by batches of storage,
+// model.ErrAliasDuplicated of item is returned in its result, other errors fail whole batch
+func (s *Storage) SaveBatch(batch []model.BatchItem) ([]model.BatchResult, error) {
+	results := make([]model.BatchResult, len(batch))
+	items := make([]model.Item, len(batch))
+	var pending []int
+	created := time.Now()
+	for i, b := range batch {
+		code, err := s.findExists(b.Item, b.TryFindExists)
+		if err != nil {
+			if !errors.Is(err, model.ErrAliasDuplicated) {
+				return nil, err
+			}
+			results[i].Err = err
+			continue
+		}
+		if code != "" {
+			results[i].Code = code
+			continue
+		}
+		items[i] = b.Item
+		items[i].Created = created
+		pending = append(pending, i)
+	}
+
+	collisionCount := 0
+	for round := 0; len(pending) > 0; round++ {
+		if round > maxCollisions {
+			return nil, errors.New("Collission happened more than 1000 times")
+		}
+		batchItems := make([]model.Item, 0, len(pending))
+		for _, i := range pending {
+			id, err := s.nextID(&collisionCount)
+			if err != nil {
+				return nil, err
+			}
+			items[i].Id = id
+			batchItems = append(batchItems, items[i])
+		}
+		errs, err := s.client.CreateBatch(batchItems)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't storage save batch")
+		}
+		// items with collided id are retried with new ids
+		var collided []int
+		for j, i := range pending {
+			switch {
+			case errs[j] == nil:
+				results[i].Code = s.getCode(items[i])
+			case errors.Is(errs[j], model.ErrAliasDuplicated):
+				results[i].Err = errs[j]
+			case errors.Is(errs[j], model.ErrItemDuplicated):
+				collided = append(collided, i)
+			default:
+				return nil, errors.Wrap(errs[j], "Can't storage save batch")
+			}
+		}
+		collisionCount += len(collided)
+		pending = collided
+	}
+
+	if collisionCount != 0 {
+		metrics.IdCollisionCounter.Add(float64(collisionCount))
+		log.Warnf("Collision on save batch of unique short URL names: %v times", collisionCount)
+	}
+
+	return results, nil
+}
+
+// nextID draws id, which code isn't an alias of existing link, because aliases are resolved before codes,
+// drawn codes of aliases are added to collision count
+func (s *Storage) nextID(collisionCount *int) (uint64, error) {
+	for shadowed := 0; shadowed <= maxCollisions; shadowed++ {
+		id, err := s.idGen.NextID()
+		if err != nil {
+			return 0, errors.Wrap(err, "Can't generate id")
+		}
+		code := s.codec.Encode(id)
+		if s.mayBeAlias != nil && !s.mayBeAlias(code) {
+			return id, nil
+		}
+		aliasId, err := s.client.FindAlias(code)
+		if err != nil {
+			return 0, errors.Wrap(err, "Can't check alias of code")
+		}
+		if aliasId == 0 {
+			return id, nil
+		}
+		*collisionCount++
+	}
+	return 0, errors.New("Collission happened more than 1000 times")
+}
+
+// findExists checks alias of item and returns code of existing link with the same url,
+// if it may be reused, empty code means that item must be created
+func (s *Storage) findExists(item model.Item, tryFindExists bool) (string, error) {
+	if item.Alias != "" {
+		return "", s.checkAliasShadowing(item.Alias)
+	}
+	if !tryFindExists || !item.IsReusable() {
+		return "", nil
+	}
+	id, err := s.client.Find(item.URL)
+	if err != nil {
+		return "", errors.Wrap(err, "Can't storage try find exists")
+	}
+	if id == 0 {
+		return "", nil
+	}
+	// protected and limited links are not shared with another creator
+	found, err := s.client.Load(id)
+	if err != nil && !errors.Is(err, model.ErrNoLink) {
+		return "", errors.Wrap(err, "Can't storage try find exists")
+	}
+	if err == nil && found.IsReusable() {
+		return s.codec.Encode(id), nil
+	}
+	return "", nil
+}
+
+// getCode returns short code of item, which is the alias if it is set
+func (s *Storage) getCode(item model.Item) string {
+	if item.Alias != "" {
+		return item.Alias
+	}
+	return s.codec.Encode(item.Id)
+}
+
+// checkAliasShadowing rejects alias which is also a short code of existing link,
+// because aliases are resolved before ids
+func (s *Storage) checkAliasShadowing(alias string) error {
+	id, err := s.codec.Decode(alias)
+	if err != nil {
+		return nil
+	}
+	_, err = s.client.Get(id)
+	if err == nil {
+		return model.ErrAliasDuplicated
+	}
+	if errors.Is(err, model.ErrNoLink) {
+		return nil
+	}
+	return errors.Wrap(err, "Can't storage check alias")
+}
+
+func (s *Storage) Load(id uint64) (model.Item, error) {
 	return s.client.Load(id)
 }
 
+func (s *Storage) LoadByAlias(alias string) (model.Item, error) {
+	return s.client.LoadByAlias(alias)
+}
+
+// Get returns item by id, expired items are returned too until they are cleaned
+func (s *Storage) Get(id uint64) (model.Item, error) {
+	return s.client.Get(id)
+}
+
+// FindAlias returns id of item by alias, 0 if alias is not used
+func (s *Storage) FindAlias(alias string) (uint64, error) {
+	return s.client.FindAlias(alias)
+}
+
+// Update changes url, expires, activeFrom, expiredUrl, redirectType, query policy and campaign of existing item
+func (s *Storage) Update(item model.Item) error {
+	return s.client.Update(item)
+}
+
+// Visit counts redirect of item with visit limit, model.ErrNoLink is returned if no visits are left
+func (s *Storage) Visit(id uint64) error {
+	return s.client.Visit(id)
+}
+
+func (s *Storage) Delete(id uint64) error {
+	return s.client.Delete(id)
+}
+
+func (s *Storage) List(query model.ListQuery) ([]model.Item, error) {
+	return s.client.List(query)
+}
+
+func (s *Storage) SaveClicks(clicks []model.Click) error {
+	return s.client.SaveClicks(clicks)
+}
+
+func (s *Storage) ClickStat(id uint64) (model.ClickStat, error) {
+	return s.client.ClickStat(id)
+}
+
+// CampaignClickStat sums click statistics of all links matching query, query.Campaign must be set
+func (s *Storage) CampaignClickStat(query model.ListQuery) (model.ClickStat, error) {
+	var stat model.ClickStat
+	query.Limit = campaignStatPage
+	query.Desc = false
+	for {
+		items, err := s.client.List(query)
+		if err != nil {
+			return model.ClickStat{}, errors.Wrapf(err, "Can't list links of campaign %v", query.Campaign)
+		}
+		for _, item := range items {
+			itemStat, err := s.client.ClickStat(item.Id)
+			if err != nil {
+				return model.ClickStat{}, errors.Wrapf(err, "Can't get click stat of link %v", item.Id)
+			}
+			stat.Add(itemStat)
+		}
+		if len(items) < query.Limit {
+			return stat, nil
+		}
+		last := items[len(items)-1]
+		query.Cursor = &model.Cursor{Created: last.Created, Id: last.Id}
+	}
+}
+
 func (s *Storage) Close() error {
 	s.cancel()
 	return s.client.Close()
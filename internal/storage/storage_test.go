@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/base62"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/storage/bolt"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/storage/memory"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/storage/sqlite"
+)
+
+// forEachClient runs test against every backend, which works without server
+func forEachClient(t *testing.T, test func(t *testing.T, c cleanableClient)) {
+	backends := []struct {
+		name string
+		new  func(t *testing.T) (cleanableClient, error)
+	}{
+		{name: "memory", new: func(t *testing.T) (cleanableClient, error) {
+			return memory.New("")
+		}},
+		{name: "sqlite", new: func(t *testing.T) (cleanableClient, error) {
+			return sqlite.New(context.Background(), filepath.Join(t.TempDir(), "sqlite.db"), time.Second)
+		}},
+		{name: "bolt", new: func(t *testing.T) (cleanableClient, error) {
+			return bolt.New(filepath.Join(t.TempDir(), "bolt.db"), "links", time.Second)
+		}},
+	}
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			c, err := backend.new(t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = c.Close() })
+			test(t, c)
+		})
+	}
+}
+
+func TestClient_Find(t *testing.T) {
+	const url = "http://example.com/"
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name  string
+		items []model.Item
+		want  uint64
+	}{
+		{name: "absent"},
+		{name: "reusable", items: []model.Item{{Id: 1, URL: url}}, want: 1},
+		{name: "other url", items: []model.Item{{Id: 1, URL: url + "other"}}},
+		{name: "expired", items: []model.Item{{Id: 1, URL: url, Expires: &past}}},
+		{name: "protected", items: []model.Item{{Id: 1, URL: url, PasswordHash: "hash"}}},
+		{name: "limited", items: []model.Item{{Id: 1, URL: url, MaxVisits: 1}}},
+		{
+			name:  "reusable before protected",
+			items: []model.Item{{Id: 1, URL: url}, {Id: 2, URL: url, PasswordHash: "hash"}},
+			want:  1,
+		},
+		{
+			name:  "reusable before limited",
+			items: []model.Item{{Id: 1, URL: url}, {Id: 2, URL: url, MaxVisits: 1}},
+			want:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachClient(t, func(t *testing.T, c cleanableClient) {
+				for _, item := range tt.items {
+					item.Created = time.Now()
+					if !assert.NoError(t, c.Create(item)) {
+						return
+					}
+				}
+				id, err := c.Find(url)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, id)
+			})
+		})
+	}
+}
+
+func TestClient_Create(t *testing.T) {
+	tests := []struct {
+		name string
+		item model.Item
+		err  error
+	}{
+		{name: "new", item: model.Item{Id: 2, URL: "http://example.com/"}},
+		{name: "duplicated id", item: model.Item{Id: 1, URL: "http://example.com/"}, err: model.ErrItemDuplicated},
+		{name: "new alias", item: model.Item{Id: 2, URL: "http://example.com/", Alias: "other"}},
+		{name: "duplicated alias", item: model.Item{Id: 2, URL: "http://example.com/", Alias: "sale"}, err: model.ErrAliasDuplicated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachClient(t, func(t *testing.T, c cleanableClient) {
+				if !assert.NoError(t, c.Create(model.Item{Id: 1, URL: "http://example.com/", Alias: "sale", Created: time.Now()})) {
+					return
+				}
+				tt.item.Created = time.Now()
+				err := c.Create(tt.item)
+				if tt.err != nil {
+					assert.ErrorIs(t, err, tt.err)
+					return
+				}
+				assert.NoError(t, err)
+				item, err := c.Get(tt.item.Id)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.item.URL, item.URL)
+			})
+		})
+	}
+}
+
+func TestClient_VisitIsAtomic(t *testing.T) {
+	const maxVisits, visitors = 3, 20
+	tests := []struct {
+		name      string
+		maxVisits int64
+		want      int
+	}{
+		{name: "limited", maxVisits: maxVisits, want: maxVisits},
+		{name: "unlimited", want: visitors},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachClient(t, func(t *testing.T, c cleanableClient) {
+				if !assert.NoError(t, c.Create(model.Item{Id: 1, URL: "http://example.com/", MaxVisits: tt.maxVisits, Created: time.Now()})) {
+					return
+				}
+				var wg sync.WaitGroup
+				errs := make([]error, visitors)
+				for i := range errs {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						errs[i] = c.Visit(1)
+					}()
+				}
+				wg.Wait()
+
+				var visited int
+				for _, err := range errs {
+					if err == nil {
+						visited++
+					} else {
+						assert.ErrorIs(t, err, model.ErrNoLink)
+					}
+				}
+				assert.Equal(t, tt.want, visited)
+			})
+		})
+	}
+}
+
+func TestClient_CleanExpired(t *testing.T) {
+	now := time.Now()
+	before := now.Add(-time.Hour)
+	expiredLong := now.Add(-2 * time.Hour)
+	expiredRecently := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+	items := []struct {
+		item    model.Item
+		cleaned bool
+	}{
+		{item: model.Item{Id: 1, URL: "http://example.com/1", Alias: "long", Expires: &expiredLong}, cleaned: true},
+		// expired inside grace period
+		{item: model.Item{Id: 2, URL: "http://example.com/2", Expires: &expiredRecently}},
+		{item: model.Item{Id: 3, URL: "http://example.com/3", Expires: &future}},
+		{item: model.Item{Id: 4, URL: "http://example.com/4"}},
+		// exhausted items are kept until they expire
+		{item: model.Item{Id: 5, URL: "http://example.com/5", MaxVisits: 1}},
+	}
+	forEachClient(t, func(t *testing.T, c cleanableClient) {
+		for _, tt := range items {
+			tt.item.Created = now
+			if !assert.NoError(t, c.Create(tt.item)) {
+				return
+			}
+		}
+		assert.NoError(t, c.Visit(5))
+		assert.ErrorIs(t, c.Visit(5), model.ErrNoLink)
+
+		assert.NoError(t, c.CleanExpired(before))
+		for _, tt := range items {
+			_, err := c.Get(tt.item.Id)
+			if tt.cleaned {
+				assert.ErrorIs(t, err, model.ErrNoLink, "item %v is cleaned", tt.item.Id)
+			} else {
+				assert.NoError(t, err, "item %v is kept", tt.item.Id)
+			}
+		}
+		// alias of cleaned item is released
+		id, err := c.FindAlias("long")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), id)
+	})
+}
+
+// aliasCountingClient counts lookups of aliases
+type aliasCountingClient struct {
+	cleanableClient
+	lookups int
+}
+
+func (c *aliasCountingClient) FindAlias(alias string) (uint64, error) {
+	c.lookups++
+	return c.cleanableClient.FindAlias(alias)
+}
+
+// listGenerator returns ids of list one by one
+type listGenerator struct {
+	ids []uint64
+}
+
+func (g *listGenerator) NextID() (uint64, error) {
+	id := g.ids[0]
+	g.ids = g.ids[1:]
+	return id, nil
+}
+
+func TestStorage_NextID(t *testing.T) {
+	codec, err := base62.NewCodec("", 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first id is shadowed by alias equal to its code
+	shadowed, free := uint64(1000), uint64(2000)
+	tests := []struct {
+		name       string
+		mayBeAlias func(code string) bool
+		want       uint64
+		lookups    int
+		collisions int
+	}{
+		{name: "without matcher", want: free, lookups: 2, collisions: 1},
+		{name: "code may be alias", mayBeAlias: func(string) bool { return true }, want: free, lookups: 2, collisions: 1},
+		{name: "code can't be alias", mayBeAlias: func(string) bool { return false }, want: shadowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem, err := memory.New("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &aliasCountingClient{cleanableClient: mem}
+			assert.NoError(t, c.Create(model.Item{Id: 1, URL: "http://example.com/", Alias: codec.Encode(shadowed), Created: time.Now()}))
+			s := &Storage{client: c, codec: codec, idGen: &listGenerator{ids: []uint64{shadowed, free}}}
+			s.SetAliasMatcher(tt.mayBeAlias)
+
+			var collisions int
+			id, err := s.nextID(&collisions)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, id)
+			assert.Equal(t, tt.lookups, c.lookups)
+			assert.Equal(t, tt.collisions, collisions)
+		})
+	}
+}
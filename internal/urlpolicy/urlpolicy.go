@@ -0,0 +1,219 @@
+package urlpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+)
+
+const (
+	defaultMaxLength      = 2048
+	defaultReloadInterval = 10 * time.Second
+)
+
+var defaultSchemes = []string{"http", "https"}
+
+// Violation is rejection of url with the rule, which matched
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("URL is rejected by %v rule: %v", v.Rule, v.Message)
+}
+
+// domains is content of domains file, patterns are host names with wildcards, e.g. *.example.com
+type domains struct {
+	Allow []string `json:"allow"`
+	Block []string `json:"block"`
+}
+
+// Policy checks destination urls, domains file is reloaded when it is changed
+type Policy struct {
+	schemes           []string
+	maxLength         int
+	blockIpHosts      bool
+	blockPrivateHosts bool
+	domainsFile       string
+	domains           atomic.Pointer[domains]
+	modified          time.Time
+	stop              chan struct{}
+	done              chan struct{}
+}
+
+func New(conf config.URLPolicy) (*Policy, error) {
+	p := &Policy{
+		schemes:           slices.Clone(conf.Schemes),
+		maxLength:         conf.MaxLength,
+		blockIpHosts:      conf.BlockIpHosts,
+		blockPrivateHosts: conf.BlockPrivateHosts,
+		domainsFile:       conf.DomainsFile,
+		stop:              make(chan struct{}),
+		done:              make(chan struct{}),
+	}
+	if len(p.schemes) == 0 {
+		p.schemes = defaultSchemes
+	}
+	for i, scheme := range p.schemes {
+		p.schemes[i] = strings.ToLower(scheme)
+	}
+	if p.maxLength <= 0 {
+		p.maxLength = defaultMaxLength
+	}
+	p.domains.Store(&domains{})
+	if p.domainsFile == "" {
+		close(p.done)
+		return p, nil
+	}
+	if _, err := p.reload(); err != nil {
+		return nil, err
+	}
+	reloadInterval := conf.ReloadInterval.Duration
+	if reloadInterval <= 0 {
+		reloadInterval = defaultReloadInterval
+	}
+	go p.run(reloadInterval)
+	return p, nil
+}
+
+func (p *Policy) run(reloadInterval time.Duration) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			reloaded, err := p.reload()
+			if err != nil {
+				log.Errorf("Can't reload url domains, previous are kept: %+v", err)
+			} else if reloaded {
+				log.Infof("URL domains are reloaded from %v", p.domainsFile)
+			}
+		}
+	}
+}
+
+// reload reads domains file if it is modified since the last reading
+func (p *Policy) reload() (bool, error) {
+	info, err := os.Stat(p.domainsFile)
+	if err != nil {
+		return false, errors.Wrapf(err, "Can't stat domains file %v", p.domainsFile)
+	}
+	if info.ModTime().Equal(p.modified) {
+		return false, nil
+	}
+	// broken file is reported once, it is read again only after the next change
+	p.modified = info.ModTime()
+	b, err := os.ReadFile(p.domainsFile)
+	if err != nil {
+		return false, errors.Wrapf(err, "Can't read domains file %v", p.domainsFile)
+	}
+	var d domains
+	if err := json.Unmarshal(b, &d); err != nil {
+		return false, errors.Wrapf(err, "Can't unmarshal domains file %v", p.domainsFile)
+	}
+	for _, pattern := range append(d.Allow, d.Block...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return false, errors.Wrapf(err, "Invalid domain pattern %v", pattern)
+		}
+	}
+	p.domains.Store(&d)
+	return true, nil
+}
+
+// Check returns *Violation if url breaks the policy
+func (p *Policy) Check(u *url.URL) error {
+	if length := len(u.String()); length > p.maxLength {
+		return &Violation{Rule: "maxLength", Message: fmt.Sprintf("length %v exceeds %v", length, p.maxLength)}
+	}
+	if scheme := strings.ToLower(u.Scheme); !slices.Contains(p.schemes, scheme) {
+		return &Violation{Rule: "schemes", Message: fmt.Sprintf("scheme %q is not allowed", scheme)}
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return &Violation{Rule: "host", Message: "host is empty"}
+	}
+
+	ip := parseIp(host)
+	if ip != nil && p.blockIpHosts {
+		return &Violation{Rule: "blockIpHosts", Message: fmt.Sprintf("host %v is ip address", host)}
+	}
+	if p.blockPrivateHosts && isPrivate(host, ip) {
+		return &Violation{Rule: "blockPrivateHosts", Message: fmt.Sprintf("host %v is private", host)}
+	}
+
+	d := p.domains.Load()
+	if pattern, ok := match(d.Block, host); ok {
+		return &Violation{Rule: "block", Message: fmt.Sprintf("host %v matches %v", host, pattern)}
+	}
+	if _, ok := match(d.Allow, host); len(d.Allow) > 0 && !ok {
+		return &Violation{Rule: "allow", Message: fmt.Sprintf("host %v is not allowed", host)}
+	}
+	return nil
+}
+
+// parseIp returns ip of ip literal host, numeric last label means ip too, because browsers
+// resolve hosts like 2130706433 or 127.1 as ip addresses
+func parseIp(host string) net.IP {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip
+	}
+	label := host[strings.LastIndex(host, ".")+1:]
+	if label == "" {
+		return nil
+	}
+	digits := "0123456789"
+	if strings.HasPrefix(label, "0x") {
+		label, digits = label[2:], "0123456789abcdef"
+	}
+	for _, symbol := range label {
+		if !strings.ContainsRune(digits, symbol) {
+			return nil
+		}
+	}
+	// the real address is not needed, unspecified is private enough to be blocked
+	return net.IPv4zero
+}
+
+func isPrivate(host string, ip net.IP) bool {
+	if ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+			ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+	}
+	return host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
+
+func match(patterns []string, host string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func (p *Policy) Close() {
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+	close(p.stop)
+	<-p.done
+}
@@ -0,0 +1,186 @@
+package urlpolicy
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergiusd/go-scanty-url-shortener/internal/config"
+	"github.com/sergiusd/go-scanty-url-shortener/internal/model"
+)
+
+func TestParseIp(t *testing.T) {
+	tests := []struct {
+		host string
+		isIp bool
+	}{
+		{host: "127.0.0.1", isIp: true},
+		{host: "::1", isIp: true},
+		{host: "2130706433", isIp: true},
+		{host: "127.1", isIp: true},
+		{host: "0x7f.1", isIp: true},
+		{host: "0x7f000001", isIp: true},
+		{host: "example.0x1f", isIp: true},
+		{host: "example.com", isIp: false},
+		{host: "1.example.com", isIp: false},
+		{host: "0xzz", isIp: false},
+		{host: "example.", isIp: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.isIp, parseIp(tt.host) != nil)
+		})
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	file := writeDomains(t, `{"allow": ["example.com", "*.example.com", "*.ORG"], "block": ["bad.example.com", "*.bad.org"]}`)
+	p, err := New(config.URLPolicy{
+		BlockIpHosts:      true,
+		BlockPrivateHosts: true,
+		DomainsFile:       file,
+		ReloadInterval:    model.Duration{Duration: time.Hour},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer p.Close()
+
+	tests := []struct {
+		url  string
+		rule string
+	}{
+		{url: "http://example.com/path"},
+		{url: "https://sub.example.com"},
+		{url: "https://a.b.example.com"},
+		{url: "https://EXAMPLE.COM."},
+		{url: "https://sub.example.com."},
+		{url: "https://golang.org"},
+		{url: "ftp://example.com", rule: "schemes"},
+		{url: "http:///path", rule: "host"},
+		{url: "http://2130706433", rule: "blockIpHosts"},
+		{url: "http://0x7f.1", rule: "blockIpHosts"},
+		{url: "http://[::1]:8080", rule: "blockIpHosts"},
+		{url: "http://localhost.", rule: "blockPrivateHosts"},
+		{url: "http://app.localhost", rule: "blockPrivateHosts"},
+		{url: "http://bad.example.com", rule: "block"},
+		{url: "http://bad.example.com.", rule: "block"},
+		{url: "http://www.bad.org", rule: "block"},
+		{url: "http://example.net", rule: "allow"},
+		{url: "http://notexample.com", rule: "allow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.rule, checkRule(t, p, tt.url))
+		})
+	}
+}
+
+func TestPolicy_CheckPrivateIp(t *testing.T) {
+	p, err := New(config.URLPolicy{BlockPrivateHosts: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer p.Close()
+
+	tests := []struct {
+		url  string
+		rule string
+	}{
+		{url: "http://8.8.8.8"},
+		{url: "http://10.0.0.1", rule: "blockPrivateHosts"},
+		{url: "http://2130706433", rule: "blockPrivateHosts"},
+		{url: "http://0x7f.1", rule: "blockPrivateHosts"},
+		{url: "http://169.254.169.254", rule: "blockPrivateHosts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.rule, checkRule(t, p, tt.url))
+		})
+	}
+}
+
+func TestPolicy_Reload(t *testing.T) {
+	file := writeDomains(t, `{"block": ["*.bad.com"]}`)
+	p, err := New(config.URLPolicy{DomainsFile: file, ReloadInterval: model.Duration{Duration: time.Hour}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer p.Close()
+	assert.Equal(t, "block", checkRule(t, p, "http://www.bad.com"))
+
+	// unchanged file is not read again
+	reloaded, err := p.reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// broken file keeps the old domains
+	rewriteDomains(t, file, `{"block": [`, time.Minute)
+	reloaded, err = p.reload()
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.Equal(t, "block", checkRule(t, p, "http://www.bad.com"))
+
+	rewriteDomains(t, file, `{"block": ["[bad"]}`, 2*time.Minute)
+	reloaded, err = p.reload()
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.Equal(t, "block", checkRule(t, p, "http://www.bad.com"))
+
+	// fixed file replaces domains
+	rewriteDomains(t, file, `{"block": ["*.worse.com"]}`, 3*time.Minute)
+	reloaded, err = p.reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "", checkRule(t, p, "http://www.bad.com"))
+	assert.Equal(t, "block", checkRule(t, p, "http://www.worse.com"))
+}
+
+func TestNew_BrokenDomains(t *testing.T) {
+	_, err := New(config.URLPolicy{DomainsFile: writeDomains(t, `{"allow": [`)})
+	assert.Error(t, err)
+
+	_, err = New(config.URLPolicy{DomainsFile: filepath.Join(t.TempDir(), "absent.json")})
+	assert.Error(t, err)
+}
+
+// checkRule returns rule of violation, empty if url is accepted
+func checkRule(t *testing.T, p *Policy, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	err = p.Check(u)
+	if err == nil {
+		return ""
+	}
+	var violation *Violation
+	if !assert.True(t, errors.As(err, &violation), err) {
+		return ""
+	}
+	return violation.Rule
+}
+
+func writeDomains(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "domains.json")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// rewriteDomains moves modification time forward, because file may be rewritten in the same clock tick
+func rewriteDomains(t *testing.T, file, content string, shift time.Duration) {
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(shift)
+	if err := os.Chtimes(file, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,52 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// devices of clients, which routing rules of links are matched against
+const (
+	DeviceIOS     = "ios"
+	DeviceAndroid = "android"
+	DeviceDesktop = "desktop"
+	// DeviceBot is crawler, link preview of messenger or http library
+	DeviceBot = "bot"
+)
+
+var Devices = []string{DeviceIOS, DeviceAndroid, DeviceDesktop, DeviceBot}
+
+// botPattern matches crawlers, link preview fetchers and http libraries, which don't follow deep links
+var botPattern = regexp.MustCompile(`(?i)bot\b|crawl|spider|slurp|preview|facebookexternalhit|facebookcatalog|` +
+	`whatsapp|skypeuripreview|embedly|vkshare|pinterest|lighthouse|headlesschrome|` +
+	`^(curl|wget|python-requests|python-urllib|go-http-client|java|okhttp|axios|node-fetch|libwww-perl|httpie)\b`)
+
+var (
+	iosPattern     = regexp.MustCompile(`(?i)\b(iphone|ipad|ipod)\b`)
+	androidPattern = regexp.MustCompile(`(?i)\bandroid\b`)
+	// desktopPattern matches platforms of desktop browsers, mobile platforms are checked before it
+	desktopPattern = regexp.MustCompile(`(?i)\b(windows nt|macintosh|mac os x|x11|linux x86_64|cros)\b`)
+	mobilePattern  = regexp.MustCompile(`(?i)\b(mobile|tablet|windows phone|kaios)\b`)
+)
+
+// Classify returns device of client by User-Agent header, empty string is unknown device.
+// Bots are detected first, because crawlers often pretend to be mobile browsers
+func Classify(userAgent string) string {
+	userAgent = strings.TrimSpace(userAgent)
+	if userAgent == "" {
+		return ""
+	}
+	switch {
+	case botPattern.MatchString(userAgent):
+		return DeviceBot
+	case iosPattern.MatchString(userAgent):
+		return DeviceIOS
+	case androidPattern.MatchString(userAgent):
+		return DeviceAndroid
+	case mobilePattern.MatchString(userAgent):
+		return ""
+	case desktopPattern.MatchString(userAgent):
+		return DeviceDesktop
+	}
+	return ""
+}